@@ -1,34 +1,252 @@
 package sequel
 
 import (
+	"database/sql/driver"
 	"fmt"
+	"slices"
+	"sync"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-var defaultMap = pgtype.NewMap()
+// typeMap is a pgtype.Map that is safe for concurrent use.
+type typeMap struct {
+	mu sync.Mutex
+	m  *pgtype.Map
+}
+
+func newTypeMap(m *pgtype.Map) *typeMap {
+	return &typeMap{m: m}
+}
+
+func (t *typeMap) registerType(typ *pgtype.Type) {
+	t.mu.Lock()
+	t.m.RegisterType(typ)
+	t.mu.Unlock()
+}
+
+func (t *typeMap) registerDefaultPgType(value any, name string) {
+	t.mu.Lock()
+	t.m.RegisterDefaultPgType(value, name)
+	t.mu.Unlock()
+}
+
+func (t *typeMap) typeForName(name string) (*pgtype.Type, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.m.TypeForName(name)
+}
+
+func (t *typeMap) typeForValue(v any) (*pgtype.Type, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.m.TypeForValue(v)
+}
+
+// scan decodes the given text value of the type with the given oid.
+func (t *typeMap) scan(oid uint32, src []byte, dst any) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.m.Scan(oid, pgtype.TextFormatCode, src, dst)
+}
+
+// encode returns the text value of v as the type with the given oid.
+func (t *typeMap) encode(oid uint32, v any) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.m.Encode(oid, pgtype.TextFormatCode, v, nil)
+}
+
+// defaultTypes is the default type map used to encode and decode the types in
+// this package.
+var defaultTypes = newTypeMap(pgtype.NewMap())
+
+// dbTypes are the type maps of the open DBs created with WithTypeMap.
+var (
+	dbTypes   []*typeMap
+	dbTypesMu sync.RWMutex
+)
+
+// newDBTypes returns the type map of a DB with the given map, and adds it to
+// the maps used by typeForValue. It returns nil if m is nil.
+func newDBTypes(m *pgtype.Map) *typeMap {
+	if m == nil {
+		return nil
+	}
+	t := newTypeMap(m)
+	dbTypesMu.Lock()
+	dbTypes = append(dbTypes, t)
+	dbTypesMu.Unlock()
+	return t
+}
+
+func removeDBTypes(t *typeMap) {
+	dbTypesMu.Lock()
+	dbTypes = slices.DeleteFunc(dbTypes, func(tm *typeMap) bool {
+		return tm == t
+	})
+	dbTypesMu.Unlock()
+}
+
+// mapValue maps the Go type of value to the PostgreSQL type with the given name,
+// registered in the type map t. It fails if the Go type is mapped to a type
+// with a different OID in another map, see checkValue.
+func (t *typeMap) mapValue(value any, name string) error {
+	typ, ok := t.typeForName(name)
+	if !ok {
+		return fmt.Errorf("type %q is not registered", name)
+	}
+	if err := t.checkValue(value, typ.OID); err != nil {
+		return err
+	}
+	t.registerDefaultPgType(value, name)
+	return nil
+}
+
+// checkValue returns an error if the Go type of value is mapped in a type map
+// other than t to a type with an OID different from the given one. As the
+// scanners and valuers in this package do not know the DB running the query,
+// they use the first map where the Go type is mapped, see typeForValue, so a
+// conflicting mapping would use the OID of another DB.
+func (t *typeMap) checkValue(value any, oid uint32) error {
+	dbTypesMu.RLock()
+	defer dbTypesMu.RUnlock()
+	for _, tm := range append([]*typeMap{defaultTypes}, dbTypes...) {
+		if tm == t {
+			continue
+		}
+		if other, ok := tm.typeForValue(value); ok && other.OID != oid {
+			return fmt.Errorf("%T is already mapped to type %s with OID %d", value, other.Name, other.OID)
+		}
+	}
+	return nil
+}
+
+// typeForValue returns the registered PostgreSQL data type for the given value
+// and the type map where it is registered, the default map or, if it is not
+// registered there, the first map of an open DB where it is, see WithTypeMap.
+func typeForValue(v any) (*typeMap, *pgtype.Type, bool) {
+	if typ, ok := defaultTypes.typeForValue(v); ok {
+		return defaultTypes, typ, true
+	}
+	dbTypesMu.RLock()
+	defer dbTypesMu.RUnlock()
+	for _, tm := range dbTypes {
+		if typ, ok := tm.typeForValue(v); ok {
+			return tm, typ, true
+		}
+	}
+	return nil, nil, false
+}
+
+// Register the arrays of the types in this package, the arrays of the Go types
+// they are based on, like netip.Addr, are registered by pgtype.
+func init() {
+	RegisterDefaultPgType(pgtype.Array[Duration]{}, "_interval")
+	RegisterDefaultPgType(pgtype.Array[Inet]{}, "_inet")
+	RegisterDefaultPgType(pgtype.Array[CIDR]{}, "_cidr")
+}
+
+// RegisterType registers a custom PostgreSQL data type, like a domain or an
+// enum, in the default type map used by [Array] and other types in this
+// package, see [WithTypeMap] to use a type map per DB. The type OID can be
+// obtained with a query like `SELECT 'name'::regtype::oid`.
+//
+// To scan an Array[T] of a custom type, register both the element and the
+// array type, using a pgtype.ArrayCodec for the latter, and then map the Go
+// type to the array type with [RegisterDefaultPgType]:
+//
+//	RegisterType(&pgtype.Type{Name: "mood", OID: moodOID, Codec: &pgtype.EnumCodec{}})
+//	t, _ := TypeForName("mood")
+//	RegisterType(&pgtype.Type{Name: "_mood", OID: moodArrayOID, Codec: &pgtype.ArrayCodec{ElementType: t}})
+//	RegisterDefaultPgType(pgtype.Array[Mood]{}, "_mood")
+//
+// Types should be registered before using them, usually on the program
+// initialization.
+func RegisterType(t *pgtype.Type) {
+	defaultTypes.registerType(t)
+}
+
+// RegisterDefaultPgType registers a mapping of a Go type to a PostgreSQL type
+// name in the default type map used by [Array] and other types in this
+// package. Use DB.RegisterDefaultPgType for a DB created with [WithTypeMap].
+func RegisterDefaultPgType(value any, name string) {
+	defaultTypes.registerDefaultPgType(value, name)
+}
+
+// TypeForName returns the registered PostgreSQL data type for the given name.
+func TypeForName(name string) (*pgtype.Type, bool) {
+	return defaultTypes.typeForName(name)
+}
 
 // Array is a generic type that implements the sql.Scanner interface.
+//
+// The element type must be mapped to a PostgreSQL array type in the type map,
+// this includes the common Go types, like netip.Addr, time.Duration or
+// pgtype.UUID, and the [Duration], [Inet] and [CIDR] types in this package.
+// Other element types must be registered with [RegisterDefaultPgType], or in
+// the type map of a DB, see [WithTypeMap], or scanned with [ArrayScan] and an
+// explicit array type OID.
+//
+// Arrays are scanned from their text representation, so an Array[string] can
+// scan arrays of any type, like uuid[] columns, as the string form of their
+// elements.
 type Array[T any] []T
 
 // Scan implements the sql.Scanner interface on the Array.
 func (a *Array[T]) Scan(src any) error {
-	typ, ok := defaultMap.TypeForValue(pgtype.Array[T]{})
+	tm, typ, ok := typeForValue(pgtype.Array[T]{})
 	if !ok {
 		return fmt.Errorf("cannot type for %T", a)
 	}
 
 	var aa []T
-	if err := ArrayScan[T](typ.OID, src, &aa); err != nil {
+	if err := arrayScan(tm, typ.OID, src, &aa); err != nil {
 		return err
 	}
 	*a = aa
 	return nil
 }
 
+// Value implements the driver.Valuer interface on the Array. A nil Array is
+// encoded as NULL.
+func (a Array[T]) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	if len(a) == 0 {
+		return "{}", nil
+	}
+
+	tm, typ, ok := typeForValue(pgtype.Array[T]{})
+	if !ok {
+		return nil, fmt.Errorf("cannot type for %T", a)
+	}
+
+	buf, err := tm.encode(typ.OID, pgtype.Array[T]{
+		Elements: a,
+		Dims:     []pgtype.ArrayDimension{{Length: int32(len(a)), LowerBound: 1}},
+		Valid:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return string(buf), nil
+}
+
 // ArrayScan scans the source using the PostgresType with the given oid and
-// stores the result in the destination.
+// stores the result in the destination. Unlike [Array], it does not require the
+// type of the elements to be mapped to the array type:
+//
+//	var ips []netip.Addr
+//	err := ArrayScan(pgtype.InetArrayOID, src, &ips)
+//
+// The oid is looked up in the default type map, see [RegisterType].
 func ArrayScan[T any](oid uint32, src any, dest *[]T) error {
+	return arrayScan(defaultTypes, oid, src, dest)
+}
+
+func arrayScan[T any](tm *typeMap, oid uint32, src any, dest *[]T) error {
 	if src == nil {
 		*dest = nil
 		return nil
@@ -37,13 +255,13 @@ func ArrayScan[T any](oid uint32, src any, dest *[]T) error {
 	switch v := src.(type) {
 	case []byte:
 		var pgArray pgtype.Array[T]
-		if err := defaultMap.Scan(oid, pgtype.TextFormatCode, v, &pgArray); err != nil {
+		if err := tm.scan(oid, v, &pgArray); err != nil {
 			return err
 		}
 		*dest = pgArray.Elements
 		return nil
 	case string:
-		return ArrayScan(oid, []byte(v), dest)
+		return arrayScan(tm, oid, []byte(v), dest)
 	default:
 		return fmt.Errorf("unsupported type %T", v)
 	}
@@ -3,6 +3,7 @@ package sequel
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"net/netip"
 	"testing"
@@ -27,6 +28,7 @@ type arrayModel struct {
 	Integers Array[int]          `db:"integers"`
 	Varchars Array[string]       `db:"varchars"`
 	Texts    Array[string]       `db:"texts"`
+	UUIDs    Array[string]       `db:"uuids"`
 }
 
 func (m *arrayModel) Select() string { return arraySelectQ }
@@ -53,12 +55,14 @@ func TestArray_Scan(t *testing.T) {
 		Integers: []int{2, 3, 5, 7, 11, 13, 17, 19},
 		Varchars: []string{"foo", "bar", "foobar"},
 		Texts:    []string{"foobar", "barzar"},
+		UUIDs:    []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", "6ba7b811-9dad-11d1-80b4-00c04fd430c8"},
 	}
 	m2 := &arrayModel{
 		CIDRs:    nil,
 		Integers: []int{2, 3, 5, 7, 11, 13, 17, 19},
 		Varchars: []string{},
 		Texts:    []string{"foobar", "barzar"},
+		UUIDs:    []string{},
 	}
 	m3 := &arrayModel{}
 
@@ -124,6 +128,16 @@ func TestArray_Scan(t *testing.T) {
 		assert.ElementsMatch(t, []*arrayModel{m1, m2, m3}, arrays)
 	})
 
+	t.Run("select uuids", func(t *testing.T) {
+		var uuids Array[string]
+		assert.NoError(t, db.Get(ctx, &uuids, "SELECT uuids FROM array_test WHERE id = $1", m1.GetID()))
+		assert.Equal(t, m1.UUIDs, uuids)
+
+		var ids Array[string]
+		assert.NoError(t, db.Get(ctx, &ids, "SELECT array_agg(id ORDER BY id) FROM array_test WHERE id = ANY($1)", Array[string]{m1.GetID(), m2.GetID()}))
+		assert.ElementsMatch(t, []string{m1.GetID(), m2.GetID()}, ids)
+	})
+
 	t.Run("scan fail", func(t *testing.T) {
 		var a Array[arrayModel]
 		assert.Error(t, a.Scan(nil))
@@ -133,6 +147,42 @@ func TestArray_Scan(t *testing.T) {
 	})
 }
 
+func TestArray_Scan_aggregate(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	require.NoError(t, db.InsertBatch(ctx, []Model{
+		&personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")},
+		&personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")},
+		&personModel{Name: "William Dalton", Email: NullString("william@example.com")},
+	}))
+
+	var names Array[string]
+	assert.NoError(t, db.Get(ctx, &names, "SELECT array_agg(name ORDER BY name) FROM person_test"))
+	assert.Equal(t, Array[string]{"Jack Dalton", "Joe Dalton", "William Dalton"}, names)
+
+	var lengths Array[int]
+	assert.NoError(t, db.Get(ctx, &lengths, "SELECT array_agg(length(name) ORDER BY name) FROM person_test"))
+	assert.Equal(t, Array[int]{11, 10, 14}, lengths)
+
+	var emails Array[string]
+	assert.NoError(t, db.Get(ctx, &emails, "SELECT array_agg(email ORDER BY email) FROM person_test WHERE name LIKE $1", "%Dalton"))
+	assert.Equal(t, Array[string]{"jack@example.com", "joe@example.com", "william@example.com"}, emails)
+
+	// array_agg of no rows is NULL
+	assert.NoError(t, db.Get(ctx, &names, "SELECT array_agg(name) FROM person_test WHERE name = $1", "Lucky Luke"))
+	assert.Nil(t, names)
+}
+
 func TestArrayScan(t *testing.T) {
 	var gotInts []int
 	assert.NoError(t, ArrayScan(pgtype.Int4ArrayOID, `{1,2,3,4,5}`, &gotInts))
@@ -142,6 +192,14 @@ func TestArrayScan(t *testing.T) {
 	assert.NoError(t, ArrayScan[string](pgtype.TextArrayOID, []byte(`{foo,bar,zar}`), &gotStrings))
 	assert.Equal(t, []string{"foo", "bar", "zar"}, gotStrings)
 
+	var gotDurations []time.Duration
+	assert.NoError(t, ArrayScan(pgtype.IntervalArrayOID, `{"1 day 01:00:00",00:00:01}`, &gotDurations))
+	assert.Equal(t, []time.Duration{25 * time.Hour, time.Second}, gotDurations)
+
+	var gotUUIDs []string
+	assert.NoError(t, ArrayScan(pgtype.UUIDArrayOID, `{6ba7b810-9dad-11d1-80b4-00c04fd430c8}`, &gotUUIDs))
+	assert.Equal(t, []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, gotUUIDs)
+
 	var badOID []int
 	assert.Error(t, ArrayScan(pgtype.CIDArrayOID, []byte(`{1,2,3,4,5}`), &badOID))
 	assert.Nil(t, badOID)
@@ -158,3 +216,151 @@ func TestArrayScan(t *testing.T) {
 	assert.Error(t, ArrayScan(pgtype.TextArrayOID, []int{1, 2, 3, 4, 5}, &badType))
 	assert.Nil(t, badType)
 }
+
+func TestArray_Value(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     driver.Valuer
+		want      driver.Value
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok ints", Array[int]{1, 2, 3}, "{1,2,3}", assert.NoError},
+		{"ok strings", Array[string]{"foo", "bar baz", `"zar"`}, `{foo,bar baz,"\"zar\""}`, assert.NoError},
+		{"ok prefixes", Array[netip.Prefix]{netip.MustParsePrefix("10.0.0.0/8")}, "{10.0.0.0/8}", assert.NoError},
+		{"ok addrs", Array[netip.Addr]{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")}, "{10.0.0.1/32,::1/128}", assert.NoError},
+		{"ok inets", Array[Inet]{Inet(netip.MustParseAddr("10.0.0.1")), Inet(netip.MustParseAddr("::1"))}, "{10.0.0.1,::1}", assert.NoError},
+		{"ok cidrs", Array[CIDR]{CIDR(netip.MustParsePrefix("10.0.0.0/8"))}, "{10.0.0.0/8}", assert.NoError},
+		{"ok durations", Array[Duration]{Duration(time.Hour + time.Second)}, "{01:00:01}", assert.NoError},
+		{"ok empty", Array[int]{}, "{}", assert.NoError},
+		{"ok nil", Array[int](nil), nil, assert.NoError},
+		{"fail type", Array[arrayModel]{{}}, nil, assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.value.Value()
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestArray_Scan_elementTypes(t *testing.T) {
+	var addrs Array[netip.Addr]
+	assert.NoError(t, addrs.Scan(`{10.0.0.1,::1}`))
+	assert.Equal(t, Array[netip.Addr]{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")}, addrs)
+
+	var inets Array[Inet]
+	assert.NoError(t, inets.Scan([]byte(`{10.0.0.1,::1}`)))
+	assert.Equal(t, Array[Inet]{Inet(netip.MustParseAddr("10.0.0.1")), Inet(netip.MustParseAddr("::1"))}, inets)
+
+	var cidrs Array[CIDR]
+	assert.NoError(t, cidrs.Scan(`{10.0.0.0/8,2001:db8::/32}`))
+	assert.Equal(t, Array[CIDR]{CIDR(netip.MustParsePrefix("10.0.0.0/8")), CIDR(netip.MustParsePrefix("2001:db8::/32"))}, cidrs)
+
+	var durations Array[Duration]
+	assert.NoError(t, durations.Scan(`{"1 day 01:00:00",NULL}`))
+	assert.Equal(t, Array[Duration]{Duration(25 * time.Hour), 0}, durations)
+
+	var uuids Array[pgtype.UUID]
+	assert.NoError(t, uuids.Scan(`{6ba7b810-9dad-11d1-80b4-00c04fd430c8}`))
+	if assert.Len(t, uuids, 1) {
+		v, err := uuids[0].Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", v)
+	}
+
+	var badInets Array[Inet]
+	assert.Error(t, badInets.Scan(`{10.0.0.0/8}`))
+}
+
+type customText string
+
+func TestRegisterType(t *testing.T) {
+	var a Array[customText]
+	assert.Error(t, a.Scan(`{foo,bar}`))
+
+	RegisterType(&pgtype.Type{Name: "custom_text", OID: 900001, Codec: pgtype.TextCodec{}})
+	typ, ok := TypeForName("custom_text")
+	require.True(t, ok)
+	assert.Equal(t, uint32(900001), typ.OID)
+
+	RegisterType(&pgtype.Type{Name: "_custom_text", OID: 900002, Codec: &pgtype.ArrayCodec{ElementType: typ}})
+	RegisterDefaultPgType(pgtype.Array[customText]{}, "_custom_text")
+
+	assert.NoError(t, a.Scan(`{foo,bar}`))
+	assert.Equal(t, Array[customText]{"foo", "bar"}, a)
+
+	v, err := a.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "{foo,bar}", v)
+
+	_, ok = TypeForName("missing_type")
+	assert.False(t, ok)
+}
+
+type dbText string
+
+func TestWithTypeMap(t *testing.T) {
+	m := pgtype.NewMap()
+	m.RegisterType(&pgtype.Type{Name: "db_text", OID: 900030, Codec: pgtype.TextCodec{}})
+	typ, ok := m.TypeForName("db_text")
+	require.True(t, ok)
+	m.RegisterType(&pgtype.Type{Name: "_db_text", OID: 900031, Codec: &pgtype.ArrayCodec{ElementType: typ}})
+	m.RegisterDefaultPgType(pgtype.Array[dbText]{}, "_db_text")
+
+	var a Array[dbText]
+	assert.Error(t, a.Scan(`{foo,bar}`))
+
+	db, err := New(postgresDataSource, WithLazyConnect(), WithTypeMap(m))
+	require.NoError(t, err)
+	assert.NoError(t, a.Scan(`{foo,bar}`))
+	assert.Equal(t, Array[dbText]{"foo", "bar"}, a)
+	v, err := a.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "{foo,bar}", v)
+
+	// The default type map is not modified.
+	_, ok = TypeForName("db_text")
+	assert.False(t, ok)
+
+	// The map is not used once the DB is closed.
+	require.NoError(t, db.Close())
+	assert.Error(t, a.Scan(`{foo,bar}`))
+}
+
+type dbKind string
+
+func TestDB_RegisterDefaultPgType(t *testing.T) {
+	newMap := func(oid uint32) *pgtype.Map {
+		m := pgtype.NewMap()
+		m.RegisterType(&pgtype.Type{Name: "db_kind", OID: oid, Codec: pgtype.TextCodec{}})
+		typ, ok := m.TypeForName("db_kind")
+		require.True(t, ok)
+		m.RegisterType(&pgtype.Type{Name: "_db_kind", OID: oid + 1, Codec: &pgtype.ArrayCodec{ElementType: typ}})
+		return m
+	}
+
+	db1, err := New(postgresDataSource, WithLazyConnect(), WithTypeMap(newMap(900040)))
+	require.NoError(t, err)
+	db2, err := New(postgresDataSource, WithLazyConnect(), WithTypeMap(newMap(900050)))
+	require.NoError(t, err)
+	defer db2.Close()
+
+	assert.NoError(t, db1.RegisterDefaultPgType(pgtype.Array[dbKind]{}, "_db_kind"))
+	v, err := Array[dbKind]{"a", "b"}.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "{a,b}", v)
+
+	// The same Go type cannot be mapped to a different type in another DB.
+	assert.Error(t, db2.RegisterDefaultPgType(pgtype.Array[dbKind]{}, "_db_kind"))
+	assert.Error(t, db2.RegisterDefaultPgType(pgtype.Array[dbKind]{}, "_missing"))
+
+	db3, err := New(postgresDataSource, WithLazyConnect())
+	require.NoError(t, err)
+	defer db3.Close()
+	assert.Error(t, db3.RegisterDefaultPgType(pgtype.Array[dbKind]{}, "_text"))
+
+	// Once the DB is closed, the Go type can be mapped in another DB.
+	require.NoError(t, db1.Close())
+	assert.NoError(t, db2.RegisterDefaultPgType(pgtype.Array[dbKind]{}, "_db_kind"))
+}
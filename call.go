@@ -0,0 +1,50 @@
+package sequel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// callQuery returns the query used to call the function fn with n arguments.
+// The query uses `?` as the placeholder.
+func callQuery(format, fn string, n int) (string, error) {
+	if !isIdentifier(fn) {
+		return "", fmt.Errorf("invalid function name %q", fn)
+	}
+	params := make([]string, n)
+	for i := range params {
+		params[i] = "?"
+	}
+	return fmt.Sprintf(format, fn, strings.Join(params, ", ")), nil
+}
+
+// Call executes the set-returning function fn with the given arguments and
+// populates dest with the result of `SELECT * FROM fn($1, ...)`. The method
+// will fail if the destination is not a pointer to a slice.
+func (d *DB) Call(ctx context.Context, dest any, fn string, args ...any) error {
+	query, err := callQuery("SELECT * FROM %s(%s)", fn, len(args))
+	if err != nil {
+		return err
+	}
+	ctx, ex, release, err := d.acquire(ctx, d.readTimeout)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return ex.SelectContext(ctx, dest, d.Rebind(query), args...)
+}
+
+// CallScalar executes the function fn with the given arguments and returns the
+// result of `SELECT fn($1, ...)`.
+func CallScalar[T any](ctx context.Context, db *DB, fn string, args ...any) (T, error) {
+	var v T
+	query, err := callQuery("SELECT %s(%s)", fn, len(args))
+	if err != nil {
+		return v, err
+	}
+	if err := db.QueryRow(ctx, db.Rebind(query), args...).Scan(&v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
@@ -0,0 +1,73 @@
+package sequel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_Call(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p2 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p3 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2, p3}))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("call", func(t *testing.T) {
+		var got []*personModel
+		assert.NoError(t, db.Call(ctx, &got, "person_test_by_name", "%Dalton"))
+		assertEqualPersons(t, []*personModel{p3, p2}, got)
+
+		assert.NoError(t, db.Call(ctx, &got, "public.person_test_by_name", "Nobody"))
+		assert.Empty(t, got)
+	})
+
+	t.Run("call scalars", func(t *testing.T) {
+		var got []int64
+		assert.NoError(t, db.Call(ctx, &got, "person_test_count", "%Dalton"))
+		assert.Equal(t, []int64{2}, got)
+	})
+
+	t.Run("call fail", func(t *testing.T) {
+		var got []*personModel
+		assert.Error(t, db.Call(ctx, &got, "person_test_by_name"))
+		assert.Error(t, db.Call(ctx, &got, "missing_function", "%"))
+		assert.Error(t, db.Call(ctx, &got, "person_test_by_name('%'); DROP TABLE person_test; --"))
+		assert.Error(t, db.Call(ctx, got, "person_test_by_name", "%"))
+	})
+
+	t.Run("callScalar", func(t *testing.T) {
+		n, err := CallScalar[int64](ctx, db, "person_test_count", "%Dalton")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		s, err := CallScalar[string](ctx, db, "upper", "foo")
+		assert.NoError(t, err)
+		assert.Equal(t, "FOO", s)
+
+		var got []*personModel
+		assert.NoError(t, db.Call(ctx, &got, "person_test_by_name", "Lucky%"))
+		assertEqualPersons(t, []*personModel{p1}, got)
+	})
+
+	t.Run("callScalar fail", func(t *testing.T) {
+		n, err := CallScalar[int64](ctx, db, "person_test_count")
+		assert.Error(t, err)
+		assert.Zero(t, n)
+
+		_, err = CallScalar[int64](ctx, db, "count(*) FROM person_test; --")
+		assert.Error(t, err)
+	})
+}
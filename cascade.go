@@ -0,0 +1,75 @@
+package sequel
+
+import (
+	"context"
+	"fmt"
+)
+
+// Cascade describes the rows of a child table that reference a parent model
+// and that must be soft-deleted with it.
+type Cascade struct {
+	// Model is a model of the child table, only used to get the table and its
+	// columns.
+	Model Model
+	// Column is the column of the child table that references the parent id.
+	Column string
+}
+
+// CascadeTo returns a Cascade for the rows in the table of the model m where
+// the given column references the parent id.
+func CascadeTo(m Model, column string) Cascade {
+	return Cascade{Model: m, Column: column}
+}
+
+// DeleteCascade soft-deletes the parent model and the rows of the children
+// tables that reference it, in a single transaction, using the same deleted_at
+// for all of them. Foreign keys with ON DELETE CASCADE only apply to hard
+// deletes, so this allows to soft-delete an aggregate with a single call. For
+// example:
+//
+//	err := db.DeleteCascade(ctx, order,
+//		CascadeTo(&OrderItem{}, "order_id"),
+//		CascadeTo(&Payment{}, "order_id"))
+//
+// Rows already soft-deleted are not modified. It returns sql.ErrNoRows if the
+// parent does not exist. As with RunInTx, if the context already contains a
+// transaction, the deletes run in it.
+func (d *DB) DeleteCascade(ctx context.Context, parent Model, children ...Cascade) error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+
+	queries := make([]string, len(children))
+	for i, c := range children {
+		b, err := queryBuilder(c.Model)
+		if err != nil {
+			return err
+		}
+		if !hasColumn(b, c.Column) {
+			return fmt.Errorf("column %q not found in %s", c.Column, b.Table)
+		}
+		queries[i] = d.Rebind(fmt.Sprintf("UPDATE %s SET deleted_at = ? WHERE %s = ? AND deleted_at IS NULL", b.Table, c.Column))
+	}
+
+	t0 := d.clock.Now()
+	if err := d.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+		r, err := tx.Exec(tx.rebindModel(parent.Delete()), t0, parent.GetID())
+		if err != nil {
+			return err
+		}
+		if err := RowsAffected(r, 1); err != nil {
+			return err
+		}
+		for _, query := range queries {
+			if _, err := tx.Exec(query, t0, parent.GetID()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	parent.SetDeletedAt(t0)
+	return nil
+}
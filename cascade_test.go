@@ -0,0 +1,99 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/qb"
+	"go.step.sm/sequel/clock"
+)
+
+var noteSelectQ, noteInsertQ, noteUpdateQ, noteDeleteQ string
+
+func init() {
+	builder := qb.Must(&noteModel{})
+	noteSelectQ, noteInsertQ, noteUpdateQ, noteDeleteQ = Queries(builder)
+}
+
+type noteModel struct {
+	Base     `dbtable:"note_test"`
+	PersonID string `db:"person_id"`
+	Body     string `db:"body"`
+}
+
+func (m *noteModel) Select() string { return noteSelectQ }
+func (m *noteModel) Insert() string { return noteInsertQ }
+func (m *noteModel) Update() string { return noteUpdateQ }
+func (m *noteModel) Delete() string { return noteDeleteQ }
+
+func TestDB_DeleteCascade(t *testing.T) {
+	t0 := time.Now().UTC().Truncate(time.Second)
+	db, err := New(postgresDataSource, WithClock(clock.NewMock(t0)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p2 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2}))
+	n1 := &noteModel{PersonID: p1.GetID(), Body: "Shoots faster than his shadow"}
+	n2 := &noteModel{PersonID: p1.GetID(), Body: "Rides Jolly Jumper"}
+	n3 := &noteModel{PersonID: p2.GetID(), Body: "The smallest Dalton"}
+	require.NoError(t, db.InsertBatch(ctx, []Model{n1, n2, n3}))
+
+	deletedAt := func(t *testing.T, table, id string) sql.NullTime {
+		t.Helper()
+		var v sql.NullTime
+		require.NoError(t, db.Get(ctx, &v, "SELECT deleted_at FROM "+table+" WHERE id = $1", id))
+		return v
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		assert.NoError(t, db.DeleteCascade(ctx, p1, CascadeTo(&noteModel{}, "person_id")))
+		assert.True(t, p1.DeletedAt.Valid)
+		assert.Equal(t, t0, p1.DeletedAt.Time)
+
+		for _, id := range []string{n1.GetID(), n2.GetID()} {
+			v := deletedAt(t, "note_test", id)
+			assert.True(t, v.Valid)
+			assert.Equal(t, t0, v.Time.UTC())
+		}
+		assert.False(t, deletedAt(t, "note_test", n3.GetID()).Valid)
+		assert.Equal(t, t0, deletedAt(t, "person_test", p1.GetID()).Time.UTC())
+	})
+
+	t.Run("ok in transaction", func(t *testing.T) {
+		assert.Error(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			if err := db.DeleteCascade(ctx, p2, CascadeTo(&noteModel{}, "person_id")); err != nil {
+				return err
+			}
+			return sql.ErrTxDone
+		}))
+		assert.False(t, deletedAt(t, "person_test", p2.GetID()).Valid)
+		assert.False(t, deletedAt(t, "note_test", n3.GetID()).Valid)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		assert.Error(t, db.DeleteCascade(ctx, p2, CascadeTo(&noteModel{}, "missing_id")))
+		assert.ErrorIs(t, db.DeleteCascade(ctx, &personModel{
+			Base: Base{ID: "3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1"},
+		}, CascadeTo(&noteModel{}, "person_id")), sql.ErrNoRows)
+
+		dbr, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer dbr.Close()
+		assert.ErrorIs(t, dbr.DeleteCascade(ctx, p2), ErrReadOnly)
+		assert.False(t, deletedAt(t, "person_test", p2.GetID()).Valid)
+	})
+}
@@ -5,6 +5,8 @@ import "time"
 type Clock interface {
 	Now() time.Time
 	Backdate() time.Time
+	Since(t time.Time) time.Duration
+	Until(t time.Time) time.Duration
 }
 
 type clock struct{}
@@ -24,6 +26,16 @@ func (c *clock) Backdate() time.Time {
 	return time.Now().UTC().Add(-time.Minute)
 }
 
+// Since returns the time elapsed since t.
+func (c *clock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// Until returns the duration until t.
+func (c *clock) Until(t time.Time) time.Duration {
+	return time.Until(t)
+}
+
 type mock struct {
 	t time.Time
 }
@@ -36,3 +48,29 @@ func (m *mock) Now() time.Time { return m.t }
 
 // Now returns the mocked time - 1m.
 func (m *mock) Backdate() time.Time { return m.t.Add(-time.Minute) }
+
+// Since returns the time elapsed since t using the mocked time.
+func (m *mock) Since(t time.Time) time.Duration { return m.t.Sub(t) }
+
+// Until returns the duration until t using the mocked time.
+func (m *mock) Until(t time.Time) time.Duration { return t.Sub(m.t) }
+
+type funcClock struct {
+	fn func() time.Time
+}
+
+// NewFunc returns a clock that uses the given function to get the current
+// time. Backdate returns the time from fn - 1m.
+func NewFunc(fn func() time.Time) Clock { return &funcClock{fn: fn} }
+
+// Now returns the time from fn.
+func (c *funcClock) Now() time.Time { return c.fn() }
+
+// Backdate returns the time from fn - 1m.
+func (c *funcClock) Backdate() time.Time { return c.fn().Add(-time.Minute) }
+
+// Since returns the time elapsed since t using the time from fn.
+func (c *funcClock) Since(t time.Time) time.Duration { return c.fn().Sub(t) }
+
+// Until returns the duration until t using the time from fn.
+func (c *funcClock) Until(t time.Time) time.Duration { return t.Sub(c.fn()) }
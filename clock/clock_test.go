@@ -59,9 +59,38 @@ func Test_clock_Backdate(t *testing.T) {
 	}
 }
 
+func Test_clock_Since(t *testing.T) {
+	c := &clock{}
+	assert.InDelta(t, time.Hour, c.Since(time.Now().Add(-time.Hour)), float64(time.Second))
+	assert.InDelta(t, -time.Hour, c.Since(time.Now().Add(time.Hour)), float64(time.Second))
+}
+
+func Test_clock_Until(t *testing.T) {
+	c := &clock{}
+	assert.InDelta(t, time.Hour, c.Until(time.Now().Add(time.Hour)), float64(time.Second))
+	assert.InDelta(t, -time.Hour, c.Until(time.Now().Add(-time.Hour)), float64(time.Second))
+}
+
 func TestMock(t *testing.T) {
 	t0 := time.Now()
 	m := NewMock(t0)
 	assert.Equal(t, t0, m.Now())
 	assert.Equal(t, t0.Add(-time.Minute), m.Backdate())
+	assert.Equal(t, time.Hour, m.Since(t0.Add(-time.Hour)))
+	assert.Equal(t, -time.Hour, m.Since(t0.Add(time.Hour)))
+	assert.Equal(t, time.Hour, m.Until(t0.Add(time.Hour)))
+	assert.Equal(t, -time.Hour, m.Until(t0.Add(-time.Hour)))
+}
+
+func TestNewFunc(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := NewFunc(func() time.Time { return now })
+	assert.Equal(t, now, c.Now())
+	assert.Equal(t, now.Add(-time.Minute), c.Backdate())
+	assert.Equal(t, time.Hour, c.Since(now.Add(-time.Hour)))
+	assert.Equal(t, time.Hour, c.Until(now.Add(time.Hour)))
+
+	// The function is called on every use
+	now = now.Add(time.Second)
+	assert.Equal(t, now, c.Now())
 }
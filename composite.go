@@ -0,0 +1,120 @@
+package sequel
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LoadType loads the definition of the PostgreSQL data type with the given
+// name, like a composite type, an enum, or a domain, and registers it in the
+// type map of the DB, see [WithTypeMap], or with [RegisterType]. Array types can
+// be loaded using the array type name, e.g. "_address", after loading the
+// element type.
+func (d *DB) LoadType(ctx context.Context, name string) error {
+	return d.RawConn(ctx, func(conn *pgx.Conn) error {
+		t, err := conn.LoadType(ctx, name)
+		if err != nil {
+			return err
+		}
+		d.typeMap().registerType(t)
+		return nil
+	})
+}
+
+// RegisterDefaultPgType registers a mapping of a Go type to a PostgreSQL type
+// name in the type map of the DB, see [WithTypeMap], or in the default type
+// map like [RegisterDefaultPgType]. The type must be registered first, with
+// LoadType or [RegisterType].
+//
+// Values are scanned and encoded with the first type map where their Go type
+// is mapped, as the DB running the query is not known, so it fails if the Go
+// type is already mapped in another map to a type with a different OID, for
+// example, if two databases with their own maps define the same type.
+func (d *DB) RegisterDefaultPgType(value any, name string) error {
+	return d.typeMap().mapValue(value, name)
+}
+
+// Composite is a generic type that implements the sql.Scanner and
+// driver.Valuer interfaces for PostgreSQL composite types. The exported fields
+// of T are mapped, in order, to the attributes of the composite type. As with
+// sql.Null, Valid is false if the value is NULL.
+//
+// The composite type must be registered before scanning or encoding a valid
+// value, and T must be mapped to it. For example, for a type created with
+// `CREATE TYPE address AS (street text, number int)`:
+//
+//	type Address struct {
+//		Street string
+//		Number int32
+//	}
+//
+//	if err := db.LoadType(ctx, "address"); err != nil {
+//		return err
+//	}
+//	if err := db.RegisterDefaultPgType(Address{}, "address"); err != nil {
+//		return err
+//	}
+//
+// Instead of LoadType, the type can be registered with [RegisterType] and a
+// pgtype.CompositeCodec if the OID and the attribute types are known, and
+// mapped with [RegisterDefaultPgType].
+type Composite[T any] struct {
+	V     T
+	Valid bool
+}
+
+// NewComposite returns a valid Composite with the given value.
+func NewComposite[T any](v T) Composite[T] {
+	return Composite[T]{V: v, Valid: true}
+}
+
+// Scan implements the sql.Scanner interface on the Composite.
+func (c *Composite[T]) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*c = Composite[T]{}
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+
+	var zero T
+	tm, typ, ok := typeForValue(zero)
+	if !ok {
+		return fmt.Errorf("cannot find type for %T", zero)
+	}
+
+	var v T
+	if err := tm.scan(typ.OID, b, &v); err != nil {
+		return err
+	}
+	*c = Composite[T]{V: v, Valid: true}
+	return nil
+}
+
+// Value implements the driver.Valuer interface on the Composite. An invalid
+// Composite is encoded as NULL.
+func (c Composite[T]) Value() (driver.Value, error) {
+	if !c.Valid {
+		return nil, nil
+	}
+
+	tm, typ, ok := typeForValue(c.V)
+	if !ok {
+		return nil, fmt.Errorf("cannot find type for %T", c.V)
+	}
+
+	buf, err := tm.encode(typ.OID, c.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,62 @@
+package sequel
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPoint struct {
+	Label string
+	X, Y  int32
+}
+
+func TestComposite(t *testing.T) {
+	textType, ok := TypeForName("text")
+	require.True(t, ok)
+	int4Type, ok := TypeForName("int4")
+	require.True(t, ok)
+
+	RegisterType(&pgtype.Type{Name: "test_point", OID: 900010, Codec: &pgtype.CompositeCodec{
+		Fields: []pgtype.CompositeCodecField{
+			{Name: "label", Type: textType},
+			{Name: "x", Type: int4Type},
+			{Name: "y", Type: int4Type},
+		},
+	}})
+	RegisterDefaultPgType(testPoint{}, "test_point")
+
+	t.Run("value", func(t *testing.T) {
+		v, err := NewComposite(testPoint{Label: "the origin", X: 0, Y: 0}).Value()
+		assert.NoError(t, err)
+		assert.Equal(t, driver.Value(`(the origin,0,0)`), v)
+
+		v, err = Composite[testPoint]{}.Value()
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+	})
+
+	t.Run("scan", func(t *testing.T) {
+		var c Composite[testPoint]
+		assert.NoError(t, c.Scan(`("the origin",1,-2)`))
+		assert.Equal(t, NewComposite(testPoint{Label: "the origin", X: 1, Y: -2}), c)
+		assert.NoError(t, c.Scan([]byte(`(foo,3,4)`)))
+		assert.Equal(t, NewComposite(testPoint{Label: "foo", X: 3, Y: 4}), c)
+		assert.NoError(t, c.Scan(nil))
+		assert.Equal(t, Composite[testPoint]{}, c)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var c Composite[testPoint]
+		assert.Error(t, c.Scan(123))
+		assert.Error(t, c.Scan(`(foo,bar,4)`))
+
+		var u Composite[struct{ A string }]
+		assert.Error(t, u.Scan(`(foo)`))
+		_, err := NewComposite(struct{ A string }{"foo"}).Value()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,136 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+
+	"github.com/go-sqlx/sqlx"
+	"go.step.sm/sequel/clock"
+)
+
+// Conn is a single connection checked out from the pool. All the queries run
+// with a Conn use the same database session, so it can be used with
+// session-scoped features like advisory locks, temporary tables, or SET
+// commands. A Conn must be closed to return the connection to the pool.
+type Conn struct {
+	conn          *sqlx.Conn
+	clock         clock.Clock
+	doRebindModel bool
+	readOnly      bool
+	queryErrors   bool
+	trackQueries  bool
+	txObserver    TxObserver
+	openTxs       *atomic.Int64
+	leakDetector  *txLeakDetector
+}
+
+// Conn checks out a single connection from the pool. If an acquire timeout is
+// set, it fails with ErrPoolExhausted if it cannot get one in time.
+//
+// Session state, like settings or advisory locks, persists when the
+// connection is returned to the pool, so it must be reset before closing the
+// Conn.
+func (d *DB) Conn(ctx context.Context) (*Conn, error) {
+	conn, err := d.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{
+		conn:          conn,
+		clock:         d.clock,
+		doRebindModel: d.doRebindModel,
+		readOnly:      d.readOnly,
+		queryErrors:   d.queryErrors,
+		trackQueries:  d.trackQueries,
+		txObserver:    d.txObserver,
+		openTxs:       &d.openTxs,
+		leakDetector:  d.leakDetector,
+	}, nil
+}
+
+// Close returns the connection to the pool.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Rebind transforms a query from `?` to the DB driver's bind type.
+func (c *Conn) Rebind(query string) string {
+	return c.conn.Rebind(query)
+}
+
+// Query executes a query that returns rows, typically a SELECT. The args are
+// for any placeholder parameters in the query.
+func (c *Conn) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tagged(ctx, c.conn).QueryContext(ctx, query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row.
+// QueryRowContext always returns a non-nil value. Errors are deferred until
+// Row's Scan method is called.
+//
+// If the query selects no rows, the *Row's Scan will return ErrNoRows.
+// Otherwise, the *Row's Scan scans the first selected row and discards the
+// rest.
+func (c *Conn) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return tagged(ctx, c.conn).QueryRowContext(ctx, query, args...)
+}
+
+// Exec executes a query without returning any rows. The args are for any
+// placeholder parameters in the query.
+func (c *Conn) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if c.readOnly {
+		return nil, ErrReadOnly
+	}
+	return tagged(ctx, c.conn).ExecContext(ctx, query, args...)
+}
+
+// Get populates the given destination with the result of the given select
+// query. The destination is usually a model, but it can be a pointer to any
+// struct with db tags or to a scannable value.
+func (c *Conn) Get(ctx context.Context, dest any, query string, args ...any) error {
+	return tagged(ctx, c.conn).GetContext(ctx, dest, query, args...)
+}
+
+// GetAll populates the given destination with all the results of the given
+// select query. The method will fail if the destination is not a pointer to a
+// slice.
+func (c *Conn) GetAll(ctx context.Context, dest any, query string, args ...any) error {
+	return tagged(ctx, c.conn).SelectContext(ctx, dest, query, args...)
+}
+
+// Select populates the given model with the result of a select by id query.
+func (c *Conn) Select(ctx context.Context, dest Model, id string) (err error) {
+	defer wrapQueryError(c.queryErrors, &err, "select", dest, "", dest.Select())
+	query := dest.Select()
+	if c.doRebindModel {
+		query = c.Rebind(query)
+	}
+	return c.Get(ctx, dest, query, id)
+}
+
+// Begin begins a transaction in the connection and returns a new Tx. The
+// connection cannot be used for other queries until the transaction is
+// committed or rolled back.
+func (c *Conn) Begin(ctx context.Context) (*Tx, error) {
+	if c.readOnly {
+		return nil, ErrReadOnly
+	}
+	tx, err := c.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.openTxs.Add(1)
+	t := &Tx{
+		tx:            tx,
+		clock:         c.clock,
+		doRebindModel: c.doRebindModel,
+		queryErrors:   c.queryErrors,
+		tracker:       newQueryTracker(c.trackQueries),
+		observer:      c.txObserver,
+		started:       c.clock.Now(),
+		openTxs:       c.openTxs,
+	}
+	c.leakDetector.watch(t)
+	return t, nil
+}
@@ -0,0 +1,121 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_Conn(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.Insert(ctx, p1))
+
+	t.Run("session", func(t *testing.T) {
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err)
+		defer func() {
+			assert.NoError(t, conn.Close())
+		}()
+
+		var pid1, pid2 int
+		assert.NoError(t, conn.Get(ctx, &pid1, "SELECT pg_backend_pid()"))
+		assert.NoError(t, conn.QueryRow(ctx, "SELECT pg_backend_pid()").Scan(&pid2))
+		assert.Equal(t, pid1, pid2)
+
+		// Temporary tables are only visible in the session
+		_, err = conn.Exec(ctx, "CREATE TEMPORARY TABLE conn_test AS SELECT * FROM person_test")
+		require.NoError(t, err)
+		var names []string
+		assert.NoError(t, conn.GetAll(ctx, &names, "SELECT name FROM conn_test"))
+		assert.Equal(t, []string{"Lucky Luke"}, names)
+		_, err = conn.Exec(ctx, "DROP TABLE conn_test")
+		assert.NoError(t, err)
+
+		// Session advisory locks
+		var locked bool
+		assert.NoError(t, conn.Get(ctx, &locked, "SELECT pg_try_advisory_lock(42)"))
+		assert.True(t, locked)
+		assert.NoError(t, db.Get(ctx, &locked, "SELECT pg_try_advisory_lock(42)"))
+		assert.False(t, locked)
+		assert.NoError(t, conn.Get(ctx, &locked, "SELECT pg_advisory_unlock(42)"))
+		assert.True(t, locked)
+	})
+
+	t.Run("queries", func(t *testing.T) {
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var p personModel
+		assert.NoError(t, conn.Select(ctx, &p, p1.GetID()))
+		assertEqualPerson(t, p1, &p)
+
+		rows, err := conn.Query(ctx, conn.Rebind("SELECT name FROM person_test WHERE id = ?"), p1.GetID())
+		require.NoError(t, err)
+		var names []string
+		for rows.Next() {
+			var name string
+			assert.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		assert.NoError(t, rows.Err())
+		assert.NoError(t, rows.Close())
+		assert.Equal(t, []string{"Lucky Luke"}, names)
+
+		tx, err := conn.Begin(ctx)
+		require.NoError(t, err)
+		p2 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+		assert.NoError(t, tx.Insert(p2))
+		assert.NoError(t, tx.Rollback())
+		assert.ErrorIs(t, conn.Select(ctx, &p, p2.GetID()), sql.ErrNoRows)
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		rdb, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer rdb.Close()
+		conn, err := rdb.Conn(ctx)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.Exec(ctx, "DELETE FROM person_test")
+		assert.ErrorIs(t, err, ErrReadOnly)
+		_, err = conn.Begin(ctx)
+		assert.ErrorIs(t, err, ErrReadOnly)
+	})
+
+	t.Run("fail closed", func(t *testing.T) {
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err)
+		assert.NoError(t, conn.Close())
+		var n int
+		assert.Error(t, conn.Get(ctx, &n, "SELECT 1"))
+	})
+
+	t.Run("fail pool exhausted", func(t *testing.T) {
+		db, err := New(postgresDataSource, WithMaxOpenConnections(1), WithAcquireTimeout(100*time.Millisecond))
+		require.NoError(t, err)
+		defer db.Close()
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = db.Conn(ctx)
+		assert.ErrorIs(t, err, ErrPoolExhausted)
+	})
+}
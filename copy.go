@@ -0,0 +1,103 @@
+package sequel
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CopyTo runs the given query with `COPY (query) TO STDOUT (FORMAT CSV)` and
+// streams the rows in CSV format into w, without loading them into memory. The
+// args are for any $N placeholders in the query.
+//
+// COPY does not support query parameters, so the args are converted to SQL
+// literals and interpolated in the query. Supported args are nil and the
+// values accepted by database/sql, including types implementing
+// driver.Valuer. CopyTo fails if the database does not use a pgx driver.
+func (d *DB) CopyTo(ctx context.Context, w io.Writer, query string, args ...any) error {
+	query, err := interpolate(query, args)
+	if err != nil {
+		return err
+	}
+
+	if d.readTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.readTimeout)
+		defer cancel()
+	}
+
+	return d.RawConn(ctx, func(conn *pgx.Conn) error {
+		_, err := conn.PgConn().CopyTo(ctx, w, "COPY ("+query+") TO STDOUT (FORMAT CSV)")
+		return err
+	})
+}
+
+// interpolate replaces the $N placeholders in the query with the given args as
+// SQL literals. Placeholders in string literals, quoted identifiers,
+// dollar-quoted strings, and comments are left untouched.
+func interpolate(query string, args []any) (string, error) {
+	if len(args) == 0 {
+		return query, nil
+	}
+
+	literals := make([]string, len(args))
+	for i, arg := range args {
+		s, err := literal(arg)
+		if err != nil {
+			return "", fmt.Errorf("error converting argument $%d: %w", i+1, err)
+		}
+		literals[i] = s
+	}
+
+	return replaceParams(query, func(n int) (string, error) {
+		if n < 1 || n > len(literals) {
+			return "", fmt.Errorf("placeholder $%d has no argument", n)
+		}
+		return literals[n-1], nil
+	})
+}
+
+// literal returns the given value as an SQL literal.
+func literal(arg any) (string, error) {
+	v, err := driver.DefaultParameterConverter.ConvertValue(arg)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case int64:
+		if v < 0 {
+			return "(" + strconv.FormatInt(v, 10) + ")", nil
+		}
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return "'" + strconv.FormatFloat(v, 'g', -1, 64) + "'::float8", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []byte:
+		return `E'\\x` + hex.EncodeToString(v) + "'::bytea", nil
+	case string:
+		return quoteLiteral(v), nil
+	case time.Time:
+		return quoteLiteral(v.Format(time.RFC3339Nano)) + "::timestamptz", nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// quoteLiteral quotes the given string as an SQL escape string literal,
+// E'...', escaping backslashes and quotes, so it is read the same way whatever
+// the value of standard_conforming_strings.
+func quoteLiteral(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "E'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
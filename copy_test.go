@@ -0,0 +1,93 @@
+package sequel
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_CopyTo(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	require.NoError(t, db.InsertBatch(ctx, []Model{
+		&personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")},
+		&personModel{Name: "Jolly Jumper", Email: NullString("jolly@example.com")},
+		&personModel{Name: "O'Timmins, Joe", Email: NullString("joe@example.com")},
+	}))
+
+	t.Run("ok", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, db.CopyTo(ctx, &buf, "SELECT name, email FROM person_test ORDER BY name"))
+		assert.Equal(t, "Jolly Jumper,jolly@example.com\nLucky Luke,lucky@example.com\n\"O'Timmins, Joe\",joe@example.com\n", buf.String())
+	})
+
+	t.Run("ok with args", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, db.CopyTo(ctx, &buf, "SELECT name FROM person_test WHERE name = $1 OR email = $2 ORDER BY name",
+			"O'Timmins, Joe", "lucky@example.com"))
+		assert.Equal(t, "Lucky Luke\n\"O'Timmins, Joe\"\n", buf.String())
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Error(t, db.CopyTo(ctx, &buf, "SELECT * FROM missing_table"))
+		assert.Error(t, db.CopyTo(ctx, &buf, "SELECT $1, $2", "foo"))
+		assert.Error(t, db.CopyTo(ctx, &buf, "SELECT $1", struct{}{}))
+	})
+}
+
+func Test_interpolate(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		query   string
+		args    []any
+		want    string
+		wantErr bool
+	}{
+		{"no args", "SELECT $1", nil, "SELECT $1", false},
+		{"string", "SELECT * FROM t WHERE a = $1", []any{"it's"}, "SELECT * FROM t WHERE a = E'it''s'", false},
+		{"backslash", "SELECT $1", []any{`\' OR 1=1 --`}, `SELECT E'\\'' OR 1=1 --'`, false},
+		{"numbers", "SELECT $1, $2, 1 - $3", []any{42, 1.5, -3}, "SELECT 42, '1.5'::float8, 1 - (-3)", false},
+		{"bool and null", "SELECT $1, $2", []any{true, nil}, "SELECT true, NULL", false},
+		{"bytes", "SELECT $1", []any{[]byte("foo")}, `SELECT E'\\x666f6f'::bytea`, false},
+		{"time", "SELECT $1", []any{ts}, "SELECT E'2024-01-02T03:04:05Z'::timestamptz", false},
+		{"valuer", "SELECT $1", []any{CIText("Foo")}, "SELECT E'Foo'", false},
+		{"repeated", "SELECT $1, $1", []any{"a"}, "SELECT E'a', E'a'", false},
+		{"two digits", "SELECT $10", []any{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, "SELECT 10", false},
+		{"quoted", `SELECT '$1', "$1", $1`, []any{1}, `SELECT '$1', "$1", 1`, false},
+		{"escaped quotes", `SELECT 'it''s $1', $1`, []any{1}, `SELECT 'it''s $1', 1`, false},
+		{"comments", "SELECT $1 -- $1\n, /* $1 */ $1", []any{1}, "SELECT 1 -- $1\n, /* $1 */ 1", false},
+		{"nested comments", "SELECT /* /* $1 */ $1 */ $1", []any{1}, "SELECT /* /* $1 */ $1 */ 1", false},
+		{"dollar quotes", "SELECT $$ $1 $$, $q$ $1 $q$, $1", []any{1}, "SELECT $$ $1 $$, $q$ $1 $q$, 1", false},
+		{"fail missing arg", "SELECT $2", []any{1}, "", true},
+		{"fail comment", "SELECT $1 /* $1", []any{1}, "", true},
+		{"fail zero", "SELECT $0", []any{1}, "", true},
+		{"fail type", "SELECT $1", []any{struct{}{}}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interpolate(tt.query, tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package sequel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CTE is a builder for queries using common table expressions, queries with
+// the form `WITH a AS (...), b AS (...) SELECT ...`.
+//
+// Each fragment uses its own positional parameters starting at $1, the builder
+// renumbers them so the final query can be used with methods like Query or
+// GetAll:
+//
+//	query, args, err := sequel.NewCTE().
+//		With("recent", "SELECT * FROM orders WHERE created_at > $1", since).
+//		With("big", "SELECT * FROM recent WHERE total > $1", 1000).
+//		Build("SELECT count(*) FROM big WHERE status = $1", "paid")
+//
+// Parameters inside single-quoted literals, double-quoted identifiers,
+// dollar-quoted strings, and comments are not renumbered.
+type CTE struct {
+	recursive bool
+	names     []string
+	queries   []string
+	args      []any
+	err       error
+}
+
+// NewCTE creates a new CTE builder.
+func NewCTE() *CTE {
+	return &CTE{}
+}
+
+// Recursive marks the query as recursive, `WITH RECURSIVE`, allowing the
+// fragments to reference themselves.
+func (c *CTE) Recursive() *CTE {
+	c.recursive = true
+	return c
+}
+
+// With adds a named sub-query to the builder. The name must be a valid
+// unqualified identifier.
+func (c *CTE) With(name, query string, args ...any) *CTE {
+	if c.err != nil {
+		return c
+	}
+	if !isIdentifier(name) || strings.Contains(name, ".") {
+		c.err = fmt.Errorf("invalid name %q", name)
+		return c
+	}
+	q, err := renumberParams(query, len(c.args), len(args))
+	if err != nil {
+		c.err = fmt.Errorf("error on %q: %w", name, err)
+		return c
+	}
+	c.names = append(c.names, name)
+	c.queries = append(c.queries, q)
+	c.args = append(c.args, args...)
+	return c
+}
+
+// Build returns the final query composed of all the named sub-queries and the
+// given query, and the arguments for all of them.
+func (c *CTE) Build(query string, args ...any) (string, []any, error) {
+	if c.err != nil {
+		return "", nil, c.err
+	}
+	q, err := renumberParams(query, len(c.args), len(args))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(c.names) == 0 {
+		return q, args, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("WITH ")
+	if c.recursive {
+		b.WriteString("RECURSIVE ")
+	}
+	for i, name := range c.names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteString(" AS (")
+		b.WriteString(c.queries[i])
+		b.WriteString(")")
+	}
+	b.WriteString(" ")
+	b.WriteString(q)
+
+	allArgs := make([]any, 0, len(c.args)+len(args))
+	allArgs = append(allArgs, c.args...)
+	allArgs = append(allArgs, args...)
+	return b.String(), allArgs, nil
+}
+
+// renumberParams adds offset to the positional parameters ($1, $2, ...) in the
+// query. It fails if a parameter is greater than the number of arguments.
+func renumberParams(query string, offset, nargs int) (string, error) {
+	return replaceParams(query, func(n int) (string, error) {
+		if n == 0 || n > nargs {
+			return "", fmt.Errorf("parameter $%d out of range, got %d arguments", n, nargs)
+		}
+		return "$" + strconv.Itoa(n+offset), nil
+	})
+}
@@ -0,0 +1,99 @@
+package sequel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCTE(t *testing.T) {
+	tests := []struct {
+		name      string
+		cte       *CTE
+		query     string
+		args      []any
+		wantQuery string
+		wantArgs  []any
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok", NewCTE().
+			With("a", "SELECT * FROM person_test WHERE name = $1 AND email = $2", "foo", "foo@example.com").
+			With("b", "SELECT * FROM a WHERE created_at > $1", 123),
+			"SELECT * FROM b WHERE id = $1 OR id = $2", []any{"id1", "id2"},
+			"WITH a AS (SELECT * FROM person_test WHERE name = $1 AND email = $2), b AS (SELECT * FROM a WHERE created_at > $3) SELECT * FROM b WHERE id = $4 OR id = $5",
+			[]any{"foo", "foo@example.com", 123, "id1", "id2"}, assert.NoError},
+		{"ok reused params", NewCTE().
+			With("a", "SELECT * FROM person_test WHERE name = $1 OR email = $1", "foo"),
+			"SELECT * FROM a WHERE name = $1", []any{"bar"},
+			"WITH a AS (SELECT * FROM person_test WHERE name = $1 OR email = $1) SELECT * FROM a WHERE name = $2",
+			[]any{"foo", "bar"}, assert.NoError},
+		{"ok quotes", NewCTE().
+			With("a", `SELECT '$1' AS "$2", name FROM person_test WHERE name = $1`, "foo"),
+			"SELECT * FROM a WHERE name <> '$1' AND email = $1", []any{"bar"},
+			`WITH a AS (SELECT '$1' AS "$2", name FROM person_test WHERE name = $1) SELECT * FROM a WHERE name <> '$1' AND email = $2`,
+			[]any{"foo", "bar"}, assert.NoError},
+		{"ok comments", NewCTE().
+			With("a", "SELECT name FROM person_test -- WHERE name = $2\n WHERE /* $3 */ name = $1", "foo"),
+			"SELECT * FROM a /* $9 */ WHERE name = $1 -- $2", []any{"bar"},
+			"WITH a AS (SELECT name FROM person_test -- WHERE name = $2\n WHERE /* $3 */ name = $1) SELECT * FROM a /* $9 */ WHERE name = $2 -- $2",
+			[]any{"foo", "bar"}, assert.NoError},
+		{"ok dollar quotes", NewCTE().
+			With("a", "SELECT $$ $2 $$ AS s, $tag$ $1 $tag$ AS t WHERE $1", true),
+			"SELECT * FROM a WHERE s <> $1", []any{"bar"},
+			"WITH a AS (SELECT $$ $2 $$ AS s, $tag$ $1 $tag$ AS t WHERE $1) SELECT * FROM a WHERE s <> $2",
+			[]any{true, "bar"}, assert.NoError},
+		{"ok recursive", NewCTE().Recursive().
+			With("t", "SELECT 1 AS n UNION ALL SELECT n + 1 FROM t WHERE n < $1", 10),
+			"SELECT sum(n) FROM t", nil,
+			"WITH RECURSIVE t AS (SELECT 1 AS n UNION ALL SELECT n + 1 FROM t WHERE n < $1) SELECT sum(n) FROM t",
+			[]any{10}, assert.NoError},
+		{"ok no fragments", NewCTE(), "SELECT * FROM person_test WHERE id = $1", []any{"id"},
+			"SELECT * FROM person_test WHERE id = $1", []any{"id"}, assert.NoError},
+		{"fail name", NewCTE().With("a b", "SELECT 1"), "SELECT * FROM a", nil, "", nil, assert.Error},
+		{"fail qualified name", NewCTE().With("public.a", "SELECT 1"), "SELECT * FROM a", nil, "", nil, assert.Error},
+		{"fail fragment params", NewCTE().With("a", "SELECT $2", 1), "SELECT * FROM a", nil, "", nil, assert.Error},
+		{"fail fragment zero param", NewCTE().With("a", "SELECT $0", 1), "SELECT * FROM a", nil, "", nil, assert.Error},
+		{"fail fragment quote", NewCTE().With("a", "SELECT 'foo"), "SELECT * FROM a", nil, "", nil, assert.Error},
+		{"fail fragment comment", NewCTE().With("a", "SELECT 1 /* $1"), "SELECT * FROM a", nil, "", nil, assert.Error},
+		{"fail fragment dollar quote", NewCTE().With("a", "SELECT $$ $1"), "SELECT * FROM a", nil, "", nil, assert.Error},
+		{"fail query params", NewCTE().With("a", "SELECT $1", 1), "SELECT * FROM a WHERE n = $1", nil, "", nil, assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := tt.cte.Build(tt.query, tt.args...)
+			tt.assertion(t, err)
+			assert.Equal(t, tt.wantQuery, query)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestCTE_query(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p2 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p3 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2, p3}))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	query, args, err := NewCTE().
+		With("daltons", "SELECT * FROM person_test WHERE name LIKE $1", "%Dalton").
+		With("joes", "SELECT * FROM daltons WHERE name LIKE $1", "Jo%").
+		Build("SELECT * FROM joes WHERE email = $1", "joe@example.com")
+	require.NoError(t, err)
+
+	var got []*personModel
+	assert.NoError(t, db.GetAll(ctx, &got, query, args...))
+	assertEqualPersons(t, []*personModel{p2}, got)
+}
@@ -0,0 +1,90 @@
+package sequel
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor is the position of a row in the keyset pagination of SelectAfter, the
+// created_at and id of the last row of a page. The zero Cursor is the position
+// before the first row.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// IsZero returns true if the cursor is the zero Cursor.
+func (c Cursor) IsZero() bool {
+	return c.CreatedAt.IsZero() && c.ID == ""
+}
+
+// Encode returns the cursor encoded as an URL-safe base64 string, so it can be
+// sent to API clients, for example in a query parameter, and decoded with
+// DecodeCursor. The zero Cursor is encoded as an empty string.
+//
+// The encoded cursor is not signed nor encrypted, a client can modify it to
+// point to a different position, but not to read rows it would not get
+// otherwise.
+func (c Cursor) Encode() string {
+	if c.IsZero() {
+		return ""
+	}
+	s := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// DecodeCursor decodes a cursor encoded with Cursor.Encode. An empty string is
+// decoded as the zero Cursor. Malformed cursors return an error.
+func DecodeCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor: bad encoding")
+	}
+	createdAt, id, ok := strings.Cut(string(b), ",")
+	if !ok || id == "" {
+		return Cursor{}, errors.New("invalid cursor: missing id")
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor: bad timestamp")
+	}
+	return Cursor{CreatedAt: t, ID: id}, nil
+}
+
+// SelectAfter populates dest, a pointer to a slice, with a page of the rows in
+// the table of the model m matching the where condition, sorted by created_at
+// and id. At most limit rows are returned, starting after the given cursor,
+// usually the one of the last row of the previous page. Soft-deleted rows are
+// not included. The where condition can use `?` placeholders for the given
+// args, and an empty condition selects all the rows.
+//
+// Unlike SelectLimit, the keyset pagination does not get slower on later
+// pages, and rows inserted or deleted between requests do not shift them.
+func (d *DB) SelectAfter(ctx context.Context, dest any, m Model, where string, after Cursor, limit int, args ...any) error {
+	if limit <= 0 {
+		return fmt.Errorf("invalid limit %d", limit)
+	}
+	b, err := queryBuilder(m)
+	if err != nil {
+		return err
+	}
+	if where == "" {
+		where = "true"
+	}
+
+	where = "(" + where + ")"
+	if !after.IsZero() {
+		where += " AND (created_at, " + b.PrimaryKey + ") > (?, ?)"
+		args = append(args[:len(args):len(args)], after.CreatedAt, after.ID)
+	}
+	query := selectWhere(b, where) + " ORDER BY created_at, " + b.PrimaryKey + " LIMIT " + strconv.Itoa(limit)
+	return d.GetAll(ctx, dest, d.Rebind(query), args...)
+}
@@ -0,0 +1,123 @@
+package sequel
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/sequel/clock"
+)
+
+func TestCursor_Encode(t *testing.T) {
+	c := Cursor{
+		CreatedAt: time.Date(2024, 5, 17, 10, 30, 15, 123456000, time.FixedZone("CEST", 2*60*60)),
+		ID:        "9f2b3c1e-5a4d-4e8b-9c7f-1a2b3c4d5e6f",
+	}
+	s := c.Encode()
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString([]byte("2024-05-17T08:30:15.123456Z,9f2b3c1e-5a4d-4e8b-9c7f-1a2b3c4d5e6f")), s)
+
+	got, err := DecodeCursor(s)
+	require.NoError(t, err)
+	assert.True(t, c.CreatedAt.Equal(got.CreatedAt))
+	assert.Equal(t, c.ID, got.ID)
+
+	assert.Empty(t, Cursor{}.Encode())
+}
+
+func TestDecodeCursor(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+	tests := []struct {
+		name      string
+		s         string
+		want      Cursor
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok", encode("2024-05-17T08:30:15Z,some-id"), Cursor{CreatedAt: time.Date(2024, 5, 17, 8, 30, 15, 0, time.UTC), ID: "some-id"}, assert.NoError},
+		{"ok empty", "", Cursor{}, assert.NoError},
+		{"fail encoding", "not base64!", Cursor{}, assert.Error},
+		{"fail padded", base64.URLEncoding.EncodeToString([]byte("2024-05-17T08:30:15Z,id")), Cursor{}, assert.Error},
+		{"fail no id", encode("2024-05-17T08:30:15Z"), Cursor{}, assert.Error},
+		{"fail empty id", encode("2024-05-17T08:30:15Z,"), Cursor{}, assert.Error},
+		{"fail timestamp", encode("yesterday,some-id"), Cursor{}, assert.Error},
+		{"fail garbage", encode("\x00\xff,\x01"), Cursor{}, assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.s)
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDB_SelectAfter(t *testing.T) {
+	t0 := time.Now().UTC().Truncate(time.Second)
+	var n int
+	db, err := New(postgresDataSource, WithClock(clock.NewFunc(func() time.Time {
+		n++
+		return t0.Add(time.Duration(n) * time.Second)
+	})))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Averell Dalton", Email: NullString("averell@example.com")}
+	p2 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+	p3 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p4 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p5 := &personModel{Name: "William Dalton", Email: NullString("william@example.com")}
+	for _, p := range []*personModel{p1, p2, p3, p4, p5} {
+		require.NoError(t, db.Insert(ctx, p))
+	}
+	require.NoError(t, db.Delete(ctx, p2))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	cursor := func(p *personModel) Cursor {
+		return Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+	}
+
+	tests := []struct {
+		name  string
+		where string
+		after Cursor
+		limit int
+		args  []any
+		want  []*personModel
+	}{
+		{"first page", "", Cursor{}, 2, nil, []*personModel{p1, p3}},
+		{"second page", "", cursor(p3), 2, nil, []*personModel{p4, p5}},
+		{"last page", "", cursor(p5), 2, nil, []*personModel{}},
+		{"where", "name LIKE ?", cursor(p1), 2, []any{"% Dalton"}, []*personModel{p3, p5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []*personModel{}
+			assert.NoError(t, db.SelectAfter(ctx, &got, &personModel{}, tt.where, tt.after, tt.limit, tt.args...))
+			assertEqualPersons(t, tt.want, got)
+		})
+	}
+
+	t.Run("ok encoded", func(t *testing.T) {
+		after, err := DecodeCursor(cursor(p1).Encode())
+		require.NoError(t, err)
+		got := []*personModel{}
+		assert.NoError(t, db.SelectAfter(ctx, &got, &personModel{}, "", after, 1))
+		assertEqualPersons(t, []*personModel{p3}, got)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var got []*personModel
+		assert.Error(t, db.SelectAfter(ctx, &got, &personModel{}, "", Cursor{}, 0))
+		assert.Error(t, db.SelectAfter(ctx, &got, &personModel{}, "missing = ?", Cursor{}, 10, 1))
+	})
+}
@@ -0,0 +1,171 @@
+package sequel
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// enumType is a PostgreSQL enum type registered with RegisterEnum.
+type enumType struct {
+	name   string
+	labels []string
+}
+
+// enumTypes maps the Go types registered with RegisterEnum to their enum type.
+var (
+	enumTypes   = make(map[reflect.Type]enumType)
+	enumTypesMu sync.RWMutex
+)
+
+// RegisterEnum registers the PostgreSQL enum type with the given name, OID
+// and array type OID, and maps the string type T to it, so values of T can be
+// used in an [Enum] and in an [Array]. The labels are the values of the enum
+// type, an Enum[T] fails to scan or encode a value that is not one of them.
+// If no labels are given, the values are not validated.
+//
+// For example, for a type created with `CREATE TYPE mood AS ENUM ('sad', 'ok',
+// 'happy')`:
+//
+//	type Mood string
+//
+//	RegisterEnum[Mood]("mood", moodOID, moodArrayOID, "sad", "ok", "happy")
+//
+// Use LoadEnum to register an enum type with the definition in the database.
+func RegisterEnum[T ~string](name string, oid, arrayOID uint32, labels ...T) {
+	registerEnum(defaultTypes, name, oid, arrayOID, labels...)
+}
+
+func registerEnum[T ~string](tm *typeMap, name string, oid, arrayOID uint32, labels ...T) {
+	tm.registerType(&pgtype.Type{Name: name, OID: oid, Codec: &pgtype.EnumCodec{}})
+	t, _ := tm.typeForName(name)
+	tm.registerType(&pgtype.Type{Name: "_" + name, OID: arrayOID, Codec: &pgtype.ArrayCodec{ElementType: t}})
+
+	var zero T
+	tm.registerDefaultPgType(zero, name)
+	tm.registerDefaultPgType(pgtype.Array[T]{}, "_"+name)
+
+	s := make([]string, len(labels))
+	for i, l := range labels {
+		s[i] = string(l)
+	}
+	enumTypesMu.Lock()
+	enumTypes[reflect.TypeFor[T]()] = enumType{name: name, labels: s}
+	enumTypesMu.Unlock()
+}
+
+// LoadEnum loads the definition of the PostgreSQL enum type with the given
+// name, its OIDs and labels, and registers it for the string type T like
+// [RegisterEnum], in the type map of the DB if [WithTypeMap] is used. It fails
+// if T is already mapped to an enum type with different OIDs in the type map
+// of another DB, see DB.RegisterDefaultPgType.
+func LoadEnum[T ~string](ctx context.Context, d *DB, name string) error {
+	var (
+		oid, arrayOID uint32
+		isEnum        bool
+		labels        Array[string]
+	)
+	if err := d.QueryRow(ctx, `SELECT t.oid, t.typarray, t.typtype = 'e',
+		array_agg(e.enumlabel ORDER BY e.enumsortorder) FILTER (WHERE e.enumlabel IS NOT NULL)
+		FROM pg_type t LEFT JOIN pg_enum e ON e.enumtypid = t.oid
+		WHERE t.oid = $1::text::regtype GROUP BY t.oid`, name).Scan(&oid, &arrayOID, &isEnum, &labels); err != nil {
+		return err
+	}
+	if !isEnum {
+		return fmt.Errorf("type %q is not an enum", name)
+	}
+
+	var zero T
+	tm := d.typeMap()
+	if err := tm.checkValue(zero, oid); err != nil {
+		return err
+	}
+	if err := tm.checkValue(pgtype.Array[T]{}, arrayOID); err != nil {
+		return err
+	}
+
+	values := make([]T, len(labels))
+	for i, l := range labels {
+		values[i] = T(l)
+	}
+	registerEnum(tm, name, oid, arrayOID, values...)
+	return nil
+}
+
+// checkEnum returns an error if the type of v is not registered with
+// RegisterEnum or if v is not one of the labels of the enum type.
+func checkEnum[T ~string](v T) error {
+	enumTypesMu.RLock()
+	typ, ok := enumTypes[reflect.TypeFor[T]()]
+	enumTypesMu.RUnlock()
+	switch {
+	case !ok:
+		return fmt.Errorf("enum type for %T is not registered", v)
+	case len(typ.labels) > 0 && !slices.Contains(typ.labels, string(v)):
+		return fmt.Errorf("invalid value %q for enum %s", v, typ.name)
+	default:
+		return nil
+	}
+}
+
+// Enum is a generic type that implements the sql.Scanner and driver.Valuer
+// interfaces for columns of a PostgreSQL enum type. The enum values are scanned
+// and encoded using their text representation, and they are validated against
+// the labels of the enum type. As with sql.Null, Valid is false if the value
+// is NULL.
+//
+// The string type T must be registered with [RegisterEnum] or [LoadEnum]
+// before scanning or encoding a valid value.
+type Enum[T ~string] struct {
+	V     T
+	Valid bool
+}
+
+// NewEnum returns a valid Enum with the given value.
+func NewEnum[T ~string](v T) Enum[T] {
+	return Enum[T]{V: v, Valid: true}
+}
+
+// Scan implements the sql.Scanner interface on the Enum.
+func (e *Enum[T]) Scan(src any) error {
+	var v T
+	switch s := src.(type) {
+	case nil:
+		*e = Enum[T]{}
+		return nil
+	case string:
+		v = T(s)
+	case []byte:
+		v = T(s)
+	default:
+		return fmt.Errorf("unsupported type %T", s)
+	}
+
+	if err := checkEnum(v); err != nil {
+		return err
+	}
+	*e = Enum[T]{V: v, Valid: true}
+	return nil
+}
+
+// Value implements the driver.Valuer interface on the Enum. An invalid Enum is
+// encoded as NULL.
+func (e Enum[T]) Value() (driver.Value, error) {
+	if !e.Valid {
+		return nil, nil
+	}
+	if err := checkEnum(e.V); err != nil {
+		return nil, err
+	}
+	return string(e.V), nil
+}
+
+// String returns the value of the Enum.
+func (e Enum[T]) String() string {
+	return string(e.V)
+}
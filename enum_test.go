@@ -0,0 +1,114 @@
+package sequel
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testColor string
+
+type testMood string
+
+func TestEnum(t *testing.T) {
+	RegisterEnum[testColor]("test_color", 900020, 900021, "red", "green", "blue")
+
+	t.Run("value", func(t *testing.T) {
+		v, err := NewEnum(testColor("red")).Value()
+		assert.NoError(t, err)
+		assert.Equal(t, driver.Value("red"), v)
+		assert.Equal(t, "red", NewEnum(testColor("red")).String())
+
+		v, err = Enum[testColor]{}.Value()
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+
+		v, err = Array[testColor]{"green", "blue"}.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, driver.Value("{green,blue}"), v)
+	})
+
+	t.Run("scan", func(t *testing.T) {
+		var e Enum[testColor]
+		assert.NoError(t, e.Scan("green"))
+		assert.Equal(t, NewEnum(testColor("green")), e)
+		assert.NoError(t, e.Scan([]byte("blue")))
+		assert.Equal(t, NewEnum(testColor("blue")), e)
+		assert.NoError(t, e.Scan(nil))
+		assert.Equal(t, Enum[testColor]{}, e)
+
+		var a Array[testColor]
+		assert.NoError(t, a.Scan("{red,blue}"))
+		assert.Equal(t, Array[testColor]{"red", "blue"}, a)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var e Enum[testColor]
+		assert.Error(t, e.Scan(123))
+		assert.EqualError(t, e.Scan("yellow"), `invalid value "yellow" for enum test_color`)
+		_, err := NewEnum(testColor("yellow")).Value()
+		assert.Error(t, err)
+
+		type unregistered string
+		var u Enum[unregistered]
+		assert.Error(t, u.Scan("foo"))
+		_, err = NewEnum(unregistered("foo")).Value()
+		assert.Error(t, err)
+	})
+
+	t.Run("without labels", func(t *testing.T) {
+		type anyColor string
+		RegisterEnum[anyColor]("test_any_color", 900022, 900023)
+		var e Enum[anyColor]
+		assert.NoError(t, e.Scan("yellow"))
+		assert.Equal(t, NewEnum(anyColor("yellow")), e)
+	})
+
+	typ, ok := TypeForName("_test_color")
+	require.True(t, ok)
+	assert.Equal(t, uint32(900021), typ.OID)
+	assert.IsType(t, &pgtype.ArrayCodec{}, typ.Codec)
+}
+
+func TestLoadEnum(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	require.NoError(t, LoadEnum[testMood](ctx, db, "mood"))
+
+	var e Enum[testMood]
+	require.NoError(t, db.Get(ctx, &e, "SELECT $1::mood", NewEnum(testMood("happy"))))
+	assert.Equal(t, NewEnum(testMood("happy")), e)
+	require.NoError(t, db.Get(ctx, &e, "SELECT NULL::mood"))
+	assert.Equal(t, Enum[testMood]{}, e)
+
+	var a Array[testMood]
+	require.NoError(t, db.Get(ctx, &a, "SELECT $1::mood[]", Array[testMood]{"sad", "ok"}))
+	assert.Equal(t, Array[testMood]{"sad", "ok"}, a)
+
+	_, err = db.Exec(ctx, "SELECT $1::mood", NewEnum(testMood("angry")))
+	assert.Error(t, err)
+
+	assert.Error(t, LoadEnum[testMood](ctx, db, "address"))
+	assert.Error(t, LoadEnum[testMood](ctx, db, "missing_type"))
+
+	t.Run("typeMap", func(t *testing.T) {
+		m := pgtype.NewMap()
+		db, err := New(postgresDataSource, WithTypeMap(m))
+		require.NoError(t, err)
+		defer db.Close()
+
+		require.NoError(t, LoadEnum[testMood](ctx, db, "mood"))
+		typ, ok := m.TypeForName("_mood")
+		require.True(t, ok)
+		assert.IsType(t, &pgtype.ArrayCodec{}, typ.Codec)
+	})
+}
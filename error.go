@@ -0,0 +1,42 @@
+package sequel
+
+import "fmt"
+
+// QueryError is the error returned by the model operations of DB and Tx when
+// the database is created with [WithQueryErrors]. It adds the operation, the
+// table and the query to the underlying error, that can still be checked with
+// errors.Is or errors.As, or helpers like IsUniqueViolation.
+type QueryError struct {
+	Op    string
+	Table string
+	Query string
+	Err   error
+}
+
+// Error implements the error interface on the QueryError.
+func (e *QueryError) Error() string {
+	if e.Table == "" {
+		return fmt.Sprintf("%s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Table, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// wrapQueryError replaces the error in errp with a QueryError if enabled is
+// true and the error is not nil. If table is empty, the table of the model m, if
+// any, is used.
+func wrapQueryError(enabled bool, errp *error, op string, m any, table, query string) {
+	if !enabled || *errp == nil {
+		return
+	}
+	if table == "" && m != nil {
+		if b, err := queryBuilder(m); err == nil {
+			table = b.Table
+		}
+	}
+	*errp = &QueryError{Op: op, Table: table, Query: query, Err: *errp}
+}
@@ -0,0 +1,143 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryError(t *testing.T) {
+	errTest := errors.New("test error")
+	tests := []struct {
+		name string
+		err  *QueryError
+		want string
+	}{
+		{"ok", &QueryError{Op: "insert", Table: "person_test", Query: personInsertQ, Err: errTest}, "insert person_test: test error"},
+		{"ok without table", &QueryError{Op: "insert batch", Err: errTest}, "insert batch: test error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.err, tt.want)
+			assert.ErrorIs(t, tt.err, errTest)
+			assert.Equal(t, errTest, tt.err.Unwrap())
+		})
+	}
+}
+
+func Test_wrapQueryError(t *testing.T) {
+	errTest := errors.New("test error")
+
+	err := errTest
+	wrapQueryError(false, &err, "insert", &personModel{}, "", personInsertQ)
+	assert.Equal(t, errTest, err)
+
+	err = nil
+	wrapQueryError(true, &err, "insert", &personModel{}, "", personInsertQ)
+	assert.NoError(t, err)
+
+	err = errTest
+	wrapQueryError(true, &err, "insert", &personModel{}, "", personInsertQ)
+	assert.Equal(t, &QueryError{Op: "insert", Table: "person_test", Query: personInsertQ, Err: errTest}, err)
+
+	err = errTest
+	wrapQueryError(true, &err, "insert", &personModel{}, "person_test_2024", "")
+	assert.Equal(t, &QueryError{Op: "insert", Table: "person_test_2024", Err: errTest}, err)
+
+	err = errTest
+	wrapQueryError(true, &err, "insert batch", nil, "", "")
+	assert.Equal(t, &QueryError{Op: "insert batch", Err: errTest}, err)
+}
+
+func TestDB_queryErrors(t *testing.T) {
+	db, err := New(postgresDataSource, WithQueryErrors())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.Insert(ctx, p1))
+
+	assertQueryError := func(t *testing.T, err error, op, query string) {
+		t.Helper()
+		var qerr *QueryError
+		if assert.ErrorAs(t, err, &qerr) {
+			assert.Equal(t, op, qerr.Op)
+			assert.Equal(t, "person_test", qerr.Table)
+			assert.Equal(t, query, qerr.Query)
+		}
+	}
+
+	t.Run("insert", func(t *testing.T) {
+		err := db.Insert(ctx, &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")})
+		assert.True(t, IsUniqueViolation(err))
+		assertQueryError(t, err, "insert", personInsertQ)
+	})
+
+	t.Run("insertBatch", func(t *testing.T) {
+		err := db.InsertBatch(ctx, []Model{
+			&personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")},
+			&personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")},
+		})
+		assert.True(t, IsUniqueViolation(err))
+		assertQueryError(t, err, "insert batch", personInsertQ)
+	})
+
+	t.Run("select", func(t *testing.T) {
+		var p personModel
+		err := db.Select(ctx, &p, "3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1")
+		assert.True(t, IsErrNotFound(err))
+		assertQueryError(t, err, "select", personSelectQ)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		p := &personModel{Base: Base{ID: "3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1"}, Name: "Ghost", Email: NullString("ghost@example.com")}
+		err := db.Update(ctx, p)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assertQueryError(t, err, "update", personUpdateQ)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		p := &personModel{Base: Base{ID: "3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1"}}
+		err := db.Delete(ctx, p)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assertQueryError(t, err, "delete", personDeleteQ)
+	})
+
+	t.Run("tx", func(t *testing.T) {
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		var p personModel
+		err = tx.Select(&p, "3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1")
+		assert.True(t, IsErrNotFound(err))
+		assertQueryError(t, err, "select", personSelectQ)
+
+		err = tx.Insert(&personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")})
+		assert.True(t, IsUniqueViolation(err))
+		assertQueryError(t, err, "insert", personInsertQ)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		db, err := New(postgresDataSource)
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Insert(ctx, &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")})
+		assert.True(t, IsUniqueViolation(err))
+		var qerr *QueryError
+		assert.False(t, errors.As(err, &qerr))
+	})
+}
@@ -0,0 +1,76 @@
+package sequel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// errExplainRollback is returned by the function run by explain to roll back
+// the transaction.
+var errExplainRollback = errors.New("explain rollback")
+
+// Explain runs the given query with `EXPLAIN (ANALYZE, BUFFERS, FORMAT TEXT)`
+// and returns the query plan as text. The args are for any placeholder
+// parameters in the query.
+//
+// EXPLAIN ANALYZE executes the query, so it runs in a transaction that is
+// always rolled back, or in a savepoint if the context contains a transaction,
+// and the side effects of an INSERT, UPDATE or DELETE are discarded. In a DB
+// created with [WithReadOnly], the transaction is read-only, so the plans of
+// the SELECT queries can be analyzed, but the database rejects the writes.
+func (d *DB) Explain(ctx context.Context, query string, args ...any) (string, error) {
+	var lines []string
+	if err := d.explain(ctx, func(ctx context.Context, tx *Tx) error {
+		return tx.tx.SelectContext(ctx, &lines, tx.track("EXPLAIN (ANALYZE, BUFFERS, FORMAT TEXT) "+query), args...)
+	}); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// ExplainJSON runs the given query with `EXPLAIN (ANALYZE, BUFFERS, FORMAT
+// JSON)` and returns the query plan in JSON. The args are for any placeholder
+// parameters in the query.
+//
+// As with Explain, the query runs in a transaction that is always rolled back.
+func (d *DB) ExplainJSON(ctx context.Context, query string, args ...any) (json.RawMessage, error) {
+	var b []byte
+	if err := d.explain(ctx, func(ctx context.Context, tx *Tx) error {
+		return tx.tx.QueryRowContext(ctx, tx.track("EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) "+query), args...).Scan(&b)
+	}); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(b), nil
+}
+
+// explain runs fn with RunInTx and always rolls back the transaction. In a
+// read-only DB, without a transaction in the context, it runs fn in a
+// read-only transaction instead.
+func (d *DB) explain(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	if _, ok := TxFromContext(ctx); !ok && d.readOnly {
+		if d.readTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d.readTimeout)
+			defer cancel()
+		}
+		tx, err := d.BeginReadOnly(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		return fn(NewTxContext(ctx, tx), tx)
+	}
+
+	err := d.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+		if err := fn(ctx, tx); err != nil {
+			return err
+		}
+		return errExplainRollback
+	})
+	if errors.Is(err, errExplainRollback) {
+		return nil
+	}
+	return err
+}
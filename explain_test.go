@@ -0,0 +1,96 @@
+package sequel
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_Explain(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.Insert(ctx, p1))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		plan, err := db.Explain(ctx, "SELECT * FROM person_test WHERE name = $1", "Lucky Luke")
+		assert.NoError(t, err)
+		assert.Contains(t, plan, "person_test")
+		assert.Contains(t, plan, "actual time=")
+		assert.Contains(t, plan, "Execution Time:")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		plan, err := db.ExplainJSON(ctx, "SELECT * FROM person_test WHERE name = $1", "Lucky Luke")
+		assert.NoError(t, err)
+
+		var v []struct {
+			Plan struct {
+				NodeType     string `json:"Node Type"`
+				RelationName string `json:"Relation Name"`
+				ActualRows   int    `json:"Actual Rows"`
+			} `json:"Plan"`
+			ExecutionTime float64 `json:"Execution Time"`
+		}
+		require.NoError(t, json.Unmarshal(plan, &v))
+		require.Len(t, v, 1)
+		assert.Equal(t, "person_test", v[0].Plan.RelationName)
+		assert.Equal(t, 1, v[0].Plan.ActualRows)
+	})
+
+	t.Run("rolled back", func(t *testing.T) {
+		plan, err := db.Explain(ctx, "UPDATE person_test SET name = $1", "Joe Dalton")
+		assert.NoError(t, err)
+		assert.Contains(t, plan, "Update on person_test")
+		_, err = db.ExplainJSON(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+
+		var got personModel
+		require.NoError(t, db.Select(ctx, &got, p1.GetID()))
+		assert.Equal(t, "Lucky Luke", got.Name)
+
+		// In a transaction, only the explained statement is rolled back.
+		require.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			_, err := db.Explain(ctx, "DELETE FROM person_test")
+			return err
+		}))
+		require.NoError(t, db.Select(ctx, &got, p1.GetID()))
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		rdb, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer rdb.Close()
+
+		plan, err := rdb.Explain(ctx, "SELECT * FROM person_test WHERE name = $1", "Lucky Luke")
+		assert.NoError(t, err)
+		assert.Contains(t, plan, "actual time=")
+		_, err = rdb.ExplainJSON(ctx, "SELECT 1")
+		assert.NoError(t, err)
+
+		// The database rejects the writes.
+		_, err = rdb.Explain(ctx, "DELETE FROM person_test")
+		assert.Equal(t, "25006", sqlState(err))
+		var got personModel
+		require.NoError(t, db.Select(ctx, &got, p1.GetID()))
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		_, err := db.Explain(ctx, "SELECT * FROM missing_table")
+		assert.Error(t, err)
+		_, err = db.ExplainJSON(ctx, "SELECT * FROM missing_table")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,12 @@
+package sequel
+
+import "regexp"
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)?$`)
+
+// isIdentifier returns true if the given string is a valid, optionally
+// schema-qualified, unquoted SQL identifier. It is used to validate names that
+// cannot be sent as query parameters and must be included in the query.
+func isIdentifier(s string) bool {
+	return identifierRegexp.MatchString(s)
+}
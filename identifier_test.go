@@ -0,0 +1,33 @@
+package sequel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want bool
+	}{
+		{"ok", "person_test", true},
+		{"ok upper", "PersonTest", true},
+		{"ok qualified", "public.person_test", true},
+		{"ok dollar", "foo$bar", true},
+		{"ok underscore", "_foo", true},
+		{"fail empty", "", false},
+		{"fail number", "1foo", false},
+		{"fail space", "foo bar", false},
+		{"fail quote", `foo"bar`, false},
+		{"fail semicolon", "foo;DROP TABLE person_test", false},
+		{"fail parenthesis", "foo()", false},
+		{"fail multiple dots", "a.b.c", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isIdentifier(tt.s))
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package sequel
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// GetJSON populates dest with the result of the given select query. The query
+// must return a single json or jsonb column, like the ones generated with
+// json_agg or jsonb_build_object, that will be unmarshaled into dest.
+//
+// If the column is NULL, for example if json_agg does not aggregate any row,
+// dest is unmarshaled from a JSON null: slices, maps and pointers will be set to
+// nil and other values will be left untouched. If the query selects no rows,
+// GetJSON returns sql.ErrNoRows.
+func (d *DB) GetJSON(ctx context.Context, dest any, query string, args ...any) error {
+	var b []byte
+	if err := d.retry(ctx, func() error {
+		ctx, ex, release, err := d.acquire(ctx, d.readTimeout)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return ex.QueryRowContext(ctx, query, args...).Scan(&b)
+	}); err != nil {
+		return err
+	}
+	if b == nil {
+		b = []byte("null")
+	}
+	return json.Unmarshal(b, dest)
+}
+
+// NullJSON is a generic type that implements the sql.Scanner and driver.Valuer
+// interfaces for nullable json and jsonb columns. As with sql.Null, Valid is
+// false if the value is NULL, which distinguishes the absence of a document
+// from an empty one. A JSON null document is scanned as a valid value.
+type NullJSON[T any] struct {
+	V     T
+	Valid bool
+}
+
+// NewNullJSON returns a valid NullJSON with the given value.
+func NewNullJSON[T any](v T) NullJSON[T] {
+	return NullJSON[T]{V: v, Valid: true}
+}
+
+// Scan implements the sql.Scanner interface on the NullJSON.
+func (n *NullJSON[T]) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*n = NullJSON[T]{}
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*n = NullJSON[T]{V: v, Valid: true}
+	return nil
+}
+
+// Value implements the driver.Valuer interface on the NullJSON. An invalid
+// NullJSON is encoded as NULL.
+func (n NullJSON[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	b, err := json.Marshal(n.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,118 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_GetJSON(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p2 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2}))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	type person struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	type aggregate struct {
+		Total   int      `json:"total"`
+		Persons []person `json:"persons"`
+	}
+
+	t.Run("slice", func(t *testing.T) {
+		var got []person
+		assert.NoError(t, db.GetJSON(ctx, &got, "SELECT json_agg(p ORDER BY p.name) FROM person_test p"))
+		assert.Equal(t, []person{
+			{ID: p2.ID, Name: "Joe Dalton", Email: "joe@example.com"},
+			{ID: p1.ID, Name: "Lucky Luke", Email: "lucky@example.com"},
+		}, got)
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		var got aggregate
+		assert.NoError(t, db.GetJSON(ctx, &got, `SELECT jsonb_build_object(
+			'total', count(*),
+			'persons', jsonb_agg(jsonb_build_object('id', id, 'name', name, 'email', email))
+		) FROM person_test WHERE id = $1`, p1.GetID()))
+		assert.Equal(t, aggregate{
+			Total: 1,
+			Persons: []person{
+				{ID: p1.ID, Name: "Lucky Luke", Email: "lucky@example.com"},
+			},
+		}, got)
+	})
+
+	t.Run("null aggregate", func(t *testing.T) {
+		got := []person{{Name: "Stale"}}
+		assert.NoError(t, db.GetJSON(ctx, &got, "SELECT json_agg(p) FROM person_test p WHERE deleted_at IS NOT NULL"))
+		assert.Nil(t, got)
+
+		var agg aggregate
+		assert.NoError(t, db.GetJSON(ctx, &agg, "SELECT NULL::jsonb"))
+		assert.Equal(t, aggregate{}, agg)
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		var got []person
+		assert.ErrorIs(t, db.GetJSON(ctx, &got, "SELECT to_jsonb(p) FROM person_test p WHERE deleted_at IS NOT NULL"), sql.ErrNoRows)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var got []person
+		assert.Error(t, db.GetJSON(ctx, got, "SELECT json_agg(p) FROM person_test p"))
+		assert.Error(t, db.GetJSON(ctx, &got, "SELECT id, name FROM person_test"))
+		assert.Error(t, db.GetJSON(ctx, &got, "SELECT 'not json'"))
+	})
+}
+
+func TestNullJSON(t *testing.T) {
+	type settings struct {
+		Theme string `json:"theme"`
+	}
+
+	t.Run("scan", func(t *testing.T) {
+		var n NullJSON[settings]
+		assert.NoError(t, n.Scan(`{"theme":"dark"}`))
+		assert.Equal(t, NewNullJSON(settings{Theme: "dark"}), n)
+		assert.NoError(t, n.Scan([]byte(`{}`)))
+		assert.Equal(t, NewNullJSON(settings{}), n)
+		assert.NoError(t, n.Scan(`null`))
+		assert.Equal(t, NewNullJSON(settings{}), n)
+		assert.NoError(t, n.Scan(nil))
+		assert.Equal(t, NullJSON[settings]{}, n)
+		assert.Error(t, n.Scan(123))
+		assert.Error(t, n.Scan(`{"theme":1}`))
+	})
+
+	t.Run("value", func(t *testing.T) {
+		v, err := NewNullJSON(settings{Theme: "dark"}).Value()
+		assert.NoError(t, err)
+		assert.Equal(t, driver.Value(`{"theme":"dark"}`), v)
+		v, err = NewNullJSON(map[string]any{}).Value()
+		assert.NoError(t, err)
+		assert.Equal(t, driver.Value(`{}`), v)
+		v, err = NullJSON[settings]{}.Value()
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+		_, err = NewNullJSON(func() {}).Value()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,96 @@
+package sequel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Notification is a notification received on a channel with LISTEN.
+type Notification = pgconn.Notification
+
+type listenOptions struct {
+	debounce time.Duration
+}
+
+// ListenOption is the type of options that can be used to modify the behavior
+// of Listen.
+type ListenOption func(*listenOptions)
+
+// WithDebounce collapses the notifications received in the given window. After
+// the first notification, Listen waits for the window to elapse and delivers
+// all the notifications received, without duplicate payloads, in a single
+// batch. It is useful for consumers that only need to know that something
+// changed, like cache invalidations, and that would be flooded by bursts of
+// notifications.
+func WithDebounce(window time.Duration) ListenOption {
+	return func(o *listenOptions) {
+		o.debounce = window
+	}
+}
+
+// Listen listens for notifications on the given channel and calls fn with
+// them. By default, fn is called with each notification, use WithDebounce to
+// get batches of notifications.
+//
+// Listen uses a dedicated connection from the pool and blocks until the
+// context is done, returning the context error, or until fn returns an error,
+// returning that error. Listen fails if the database does not use a pgx
+// driver.
+func (d *DB) Listen(ctx context.Context, channel string, fn func(notifications []*Notification) error, opts ...ListenOption) error {
+	o := new(listenOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return d.RawConn(ctx, func(conn *pgx.Conn) error {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+		defer func() {
+			_, _ = conn.Exec(context.Background(), "UNLISTEN *")
+		}()
+
+		for {
+			n, err := conn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			batch := []*Notification{n}
+			if o.debounce > 0 {
+				if batch, err = collectNotifications(ctx, conn, batch, o.debounce); err != nil {
+					return err
+				}
+			}
+			if err := fn(batch); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// collectNotifications adds to the batch the notifications received in the
+// given window, skipping the ones with a payload already in the batch.
+func collectNotifications(ctx context.Context, conn *pgx.Conn, batch []*Notification, window time.Duration) ([]*Notification, error) {
+	seen := map[string]bool{batch[0].Payload: true}
+
+	wctx, cancel := context.WithTimeout(ctx, window)
+	defer cancel()
+	for {
+		n, err := conn.WaitForNotification(wctx)
+		switch {
+		case err == nil:
+			if !seen[n.Payload] {
+				seen[n.Payload] = true
+				batch = append(batch, n)
+			}
+		case ctx.Err() == nil && errors.Is(wctx.Err(), context.DeadlineExceeded):
+			return batch, nil
+		default:
+			return nil, err
+		}
+	}
+}
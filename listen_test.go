@@ -0,0 +1,98 @@
+package sequel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_Listen(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	notify := func(t *testing.T, payloads ...string) {
+		t.Helper()
+		for _, p := range payloads {
+			_, err := db.Exec(context.Background(), "SELECT pg_notify('test_channel', $1)", p)
+			require.NoError(t, err)
+		}
+	}
+
+	// listen starts listening in the background and returns a channel with
+	// the batches received, it waits until the listener is ready.
+	listen := func(t *testing.T, ctx context.Context, opts ...ListenOption) (<-chan []string, <-chan error) {
+		t.Helper()
+		batches := make(chan []string, 10)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- db.Listen(ctx, "test_channel", func(notifications []*Notification) error {
+				var payloads []string
+				for _, n := range notifications {
+					assert.Equal(t, "test_channel", n.Channel)
+					payloads = append(payloads, n.Payload)
+				}
+				batches <- payloads
+				return nil
+			}, opts...)
+		}()
+		require.Eventually(t, func() bool {
+			var n int
+			require.NoError(t, db.Get(context.Background(), &n, "SELECT count(*) FROM pg_stat_activity WHERE query = 'LISTEN \"test_channel\"'"))
+			return n == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		return batches, errc
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		batches, errc := listen(t, ctx)
+
+		notify(t, "foo", "bar", "foo")
+		assert.Equal(t, []string{"foo"}, <-batches)
+		assert.Equal(t, []string{"bar"}, <-batches)
+		assert.Equal(t, []string{"foo"}, <-batches)
+
+		cancel()
+		assert.ErrorIs(t, <-errc, context.Canceled)
+	})
+
+	t.Run("ok debounce", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		batches, errc := listen(t, ctx, WithDebounce(500*time.Millisecond))
+
+		notify(t, "foo", "bar", "foo", "foo", "zar")
+		assert.Equal(t, []string{"foo", "bar", "zar"}, <-batches)
+
+		notify(t, "foo")
+		assert.Equal(t, []string{"foo"}, <-batches)
+
+		cancel()
+		assert.ErrorIs(t, <-errc, context.Canceled)
+	})
+
+	t.Run("fail handler", func(t *testing.T) {
+		errTest := errors.New("test error")
+		errc := make(chan error, 1)
+		go func() {
+			errc <- db.Listen(context.Background(), "test_channel", func([]*Notification) error {
+				return errTest
+			})
+		}()
+		require.Eventually(t, func() bool {
+			notify(t, "foo")
+			select {
+			case err := <-errc:
+				return assert.ErrorIs(t, err, errTest)
+			default:
+				return false
+			}
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+}
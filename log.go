@@ -0,0 +1,69 @@
+package sequel
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxLoggedQueryLen is the maximum length of the queries logged by the
+// slogTracer, longer queries are truncated.
+const maxLoggedQueryLen = 1024
+
+type slogTracerKey struct{}
+
+// slogTracerData is the data of a query in progress.
+type slogTracerData struct {
+	start time.Time
+	query string
+}
+
+// slogTracer is a pgx.QueryTracer that logs the queries with a slog.Logger.
+type slogTracer struct {
+	logger *slog.Logger
+}
+
+// TraceQueryStart implements the pgx.QueryTracer interface.
+func (t *slogTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, slogTracerKey{}, &slogTracerData{
+		start: time.Now(),
+		query: data.SQL,
+	})
+}
+
+// TraceQueryEnd implements the pgx.QueryTracer interface. It logs successful
+// queries at debug level and failed ones at error level. The arguments of the
+// queries are never logged.
+func (t *slogTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	qd, ok := ctx.Value(slogTracerKey{}).(*slogTracerData)
+	if !ok {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("sql", truncateQuery(qd.query)),
+		slog.Duration("duration", time.Since(qd.start)),
+	}
+	if data.Err != nil {
+		attrs = append(attrs, slog.Any("error", data.Err))
+		t.logger.LogAttrs(ctx, slog.LevelError, "query failed", attrs...)
+		return
+	}
+	attrs = append(attrs, slog.Int64("rows", data.CommandTag.RowsAffected()))
+	t.logger.LogAttrs(ctx, slog.LevelDebug, "query", attrs...)
+}
+
+// truncateQuery truncates the query to maxLoggedQueryLen bytes, without
+// splitting a multi-byte character.
+func truncateQuery(query string) string {
+	if len(query) <= maxLoggedQueryLen {
+		return query
+	}
+	n := maxLoggedQueryLen
+	for n > 0 && !utf8.RuneStart(query[n]) {
+		n--
+	}
+	return query[:n] + "..."
+}
@@ -0,0 +1,87 @@
+package sequel
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_slogTracer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := &slogTracer{logger: slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+
+	t.Run("ok", func(t *testing.T) {
+		buf.Reset()
+		ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "UPDATE person_test SET name = $1", Args: []any{"secret"}})
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 2")})
+		assert.Contains(t, buf.String(), `level=DEBUG msg=query sql="UPDATE person_test SET name = $1" duration=`)
+		assert.Contains(t, buf.String(), "rows=2")
+		assert.NotContains(t, buf.String(), "secret")
+	})
+
+	t.Run("ok error", func(t *testing.T) {
+		buf.Reset()
+		ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1/0"})
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("division by zero")})
+		assert.Contains(t, buf.String(), `level=ERROR msg="query failed" sql="SELECT 1/0" duration=`)
+		assert.Contains(t, buf.String(), `error="division by zero"`)
+	})
+
+	t.Run("ok without start", func(t *testing.T) {
+		buf.Reset()
+		tracer.TraceQueryEnd(context.Background(), nil, pgx.TraceQueryEndData{})
+		assert.Empty(t, buf.String())
+	})
+}
+
+func Test_truncateQuery(t *testing.T) {
+	short := "SELECT 1"
+	assert.Equal(t, short, truncateQuery(short))
+
+	exact := strings.Repeat("a", maxLoggedQueryLen)
+	assert.Equal(t, exact, truncateQuery(exact))
+
+	long := strings.Repeat("a", maxLoggedQueryLen+10)
+	assert.Equal(t, exact+"...", truncateQuery(long))
+
+	// Multi-byte characters are not split.
+	multi := strings.Repeat("a", maxLoggedQueryLen-1) + "ñandú"
+	assert.Equal(t, strings.Repeat("a", maxLoggedQueryLen-1)+"...", truncateQuery(multi))
+}
+
+func TestDB_slog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	db, err := New(postgresDataSource, WithSlog(logger))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	var n int
+	require.NoError(t, db.Get(ctx, &n, "SELECT 42"))
+	assert.Contains(t, buf.String(), `level=DEBUG msg=query sql="SELECT 42"`)
+
+	_, err = db.Exec(ctx, "SELECT * FROM missing_table")
+	assert.Error(t, err)
+	assert.Contains(t, buf.String(), `level=ERROR msg="query failed" sql="SELECT * FROM missing_table"`)
+
+	sqlDB, err := sql.Open("pgx/v5", postgresDataSource)
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	_, err = NewDB(sqlDB, "pgx/v5", WithSlog(logger))
+	assert.Error(t, err)
+
+	_, err = New(postgresDataSource, WithDriver("postgres"), WithSlog(logger))
+	assert.Error(t, err)
+}
@@ -81,7 +81,9 @@ func TestMain(m *testing.M) {
 		fatal(err)
 	}
 
-	postgresDataSource = fmt.Sprintf("postgres://%s:%s@localhost:%s/%s?sslmode=disable&application_name=test", dbUser, dbPassword, postgresPort.Port(), dbName)
+	postgresDataSource = NewDSN().Host("localhost").Port(postgresPort.Int()).
+		User(dbUser).Password(dbPassword).Database(dbName).
+		SSLMode("disable").Param("application_name", "test").Build()
 
 	os.Exit(m.Run())
 }
@@ -0,0 +1,151 @@
+package sequel
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name text NOT NULL,
+	applied_at timestamptz NOT NULL
+)`
+
+// migrationsLockID is the key of the session advisory lock used to serialize
+// migrations run concurrently from different processes.
+const migrationsLockID = 7_461_936_384_627_316_051
+
+var migrationRegexp = regexp.MustCompile(`^(\d+)_(.+)\.up\.sql$`)
+
+type migration struct {
+	Version int64
+	Name    string
+	Path    string
+}
+
+// listMigrations returns the up migrations in the directory dir of fsys sorted
+// by version. Files not matching the NNN_name.up.sql format are ignored.
+func listMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := migrationRegexp.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration %s: %w", e.Name(), err)
+		}
+		migrations = append(migrations, migration{
+			Version: version,
+			Name:    m[2],
+			Path:    path.Join(dir, e.Name()),
+		})
+	}
+
+	slices.SortFunc(migrations, func(a, b migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// Migrate applies the up migrations in the directory dir of fsys, usually an
+// embed.FS. Migrations are files named NNN_name.up.sql, where NNN is the
+// version, and they are applied in version order. Each migration runs in its
+// own transaction and, once applied, its version is recorded in the
+// schema_migrations table, so it is skipped by following calls.
+//
+// Migrate runs in a single connection holding a session advisory lock, so when
+// multiple processes run it at the same time, only one of them applies the
+// migrations while the others wait for the lock and then skip them.
+//
+// Down migrations are not supported and files with other names are ignored.
+func (d *DB) Migrate(ctx context.Context, fsys fs.FS, dir string) (err error) {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+
+	migrations, err := listMigrations(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	conn, err := d.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", int64(migrationsLockID)); err != nil {
+		return fmt.Errorf("error acquiring migrations lock: %w", err)
+	}
+	defer func() {
+		// The lock must be released even if the context is canceled, as it
+		// would be kept by the connection returned to the pool.
+		if _, uerr := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", int64(migrationsLockID)); uerr != nil && err == nil {
+			err = fmt.Errorf("error releasing migrations lock: %w", uerr)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if err := d.migrate(ctx, conn, fsys, m); err != nil {
+			return fmt.Errorf("error applying migration %d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// migrate applies the given migration in the connection if it has not been
+// applied yet.
+func (d *DB) migrate(ctx context.Context, conn *Conn, fsys fs.FS, m migration) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var applied bool
+	if err := tx.Get(&applied, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", m.Version); err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+
+	b, err := fs.ReadFile(fsys, m.Path)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(string(b)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)", m.Version, m.Name, d.clock.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,131 @@
+package sequel
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_Migrate(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DROP TABLE IF EXISTS schema_migrations, migrate_test")
+		assert.NoError(t, err)
+	})
+
+	fsys := fstest.MapFS{
+		"migrations/001_create_table.up.sql": {Data: []byte(`CREATE TABLE migrate_test (
+			id serial PRIMARY KEY,
+			name text NOT NULL
+		);`)},
+		"migrations/001_create_table.down.sql":    {Data: []byte("DROP TABLE migrate_test;")},
+		"migrations/002_insert_rows.up.sql":       {Data: []byte("INSERT INTO migrate_test (name) VALUES ('foo'); INSERT INTO migrate_test (name) VALUES ('bar');")},
+		"migrations/README.md":                    {Data: []byte("# Migrations")},
+		"migrations_next/001_create_table.up.sql": {Data: []byte("CREATE TABLE migrate_test (id serial PRIMARY KEY);")},
+		"migrations_next/002_insert_rows.up.sql":  {Data: []byte("INSERT INTO migrate_test (name) VALUES ('foo'), ('bar');")},
+		"migrations_next/010_add_column.up.sql":   {Data: []byte("ALTER TABLE migrate_test ADD COLUMN email text;")},
+		"migrations_fail/001_create_table.up.sql": {Data: []byte("CREATE TABLE migrate_test (id serial PRIMARY KEY);")},
+		"migrations_fail/002_insert_rows.up.sql":  {Data: []byte("INSERT INTO migrate_test (name) VALUES ('foo'), ('bar');")},
+		"migrations_fail/010_add_column.up.sql":   {Data: []byte("ALTER TABLE migrate_test ADD COLUMN email text;")},
+		"migrations_fail/011_bad.up.sql":          {Data: []byte("ALTER TABLE migrate_test ADD COLUMN zip text; ALTER TABLE missing_table ADD COLUMN foo text;")},
+	}
+
+	count := func(t *testing.T, query string) int {
+		t.Helper()
+		var n int
+		require.NoError(t, db.Get(ctx, &n, query))
+		return n
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		// Concurrent runs apply the migrations only once.
+		var wg sync.WaitGroup
+		errs := make([]error, 5)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = db.Migrate(ctx, fsys, "migrations")
+			}()
+		}
+		wg.Wait()
+		for _, err := range errs {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, 2, count(t, "SELECT count(*) FROM migrate_test"))
+		assert.Equal(t, 2, count(t, "SELECT count(*) FROM schema_migrations"))
+		assert.Equal(t, 0, count(t, "SELECT count(*) FROM pg_locks WHERE locktype = 'advisory'"))
+	})
+
+	t.Run("ok applied", func(t *testing.T) {
+		assert.NoError(t, db.Migrate(ctx, fsys, "migrations"))
+		assert.Equal(t, 2, count(t, "SELECT count(*) FROM migrate_test"))
+		assert.Equal(t, 2, count(t, "SELECT count(*) FROM schema_migrations"))
+	})
+
+	t.Run("ok new", func(t *testing.T) {
+		assert.NoError(t, db.Migrate(ctx, fsys, "migrations_next"))
+		assert.Equal(t, 2, count(t, "SELECT count(*) FROM migrate_test"))
+		assert.Equal(t, 3, count(t, "SELECT count(*) FROM schema_migrations"))
+		assert.Equal(t, 0, count(t, "SELECT count(email) FROM migrate_test"))
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		err := db.Migrate(ctx, fsys, "migrations_fail")
+		assert.ErrorContains(t, err, "error applying migration 11_bad")
+		assert.Equal(t, 3, count(t, "SELECT count(*) FROM schema_migrations"))
+		assert.Equal(t, 0, count(t, "SELECT count(*) FROM information_schema.columns WHERE table_name = 'migrate_test' AND column_name = 'zip'"))
+	})
+
+	t.Run("fail read only", func(t *testing.T) {
+		db, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer db.Close()
+		assert.ErrorIs(t, db.Migrate(ctx, fsys, "migrations"), ErrReadOnly)
+	})
+
+	t.Run("fail dir", func(t *testing.T) {
+		assert.Error(t, db.Migrate(ctx, fsys, "missing"))
+	})
+}
+
+func Test_listMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ok/0010_third.up.sql":                       {},
+		"ok/0002_second.up.sql":                      {},
+		"ok/0001_first.up.sql":                       {},
+		"ok/0001_first.down.sql":                     {},
+		"ok/first.up.sql":                            {},
+		"ok/0003_dir.up.sql/foo":                     {},
+		"dup/001_first.up.sql":                       {},
+		"dup/0001_first_dup.up.sql":                  {},
+		"overflow/99999999999999999999_first.up.sql": {},
+	}
+
+	got, err := listMigrations(fsys, "ok")
+	assert.NoError(t, err)
+	assert.Equal(t, []migration{
+		{Version: 1, Name: "first", Path: "ok/0001_first.up.sql"},
+		{Version: 2, Name: "second", Path: "ok/0002_second.up.sql"},
+		{Version: 10, Name: "third", Path: "ok/0010_third.up.sql"},
+	}, got)
+
+	_, err = listMigrations(fsys, "dup")
+	assert.EqualError(t, err, "duplicate migration version 1")
+
+	_, err = listMigrations(fsys, "overflow")
+	assert.Error(t, err)
+
+	_, err = listMigrations(fsys, "missing")
+	assert.Error(t, err)
+}
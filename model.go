@@ -1,8 +1,16 @@
 package sequel
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"go.step.sm/qb"
 )
@@ -34,6 +42,40 @@ type ModelWithExecInsert interface {
 	WithExecInsert()
 }
 
+// ModelWithGeneratedColumns is the interface implemented by a model with
+// generated columns, like `GENERATED ALWAYS AS (...) STORED` columns. The
+// generated columns are scanned on reads but cannot be written, so they are
+// excluded from the insert and update queries generated by this package, like
+// the ones in InsertInto, Upsert, or UpdateMap. Use QueriesWithGenerated to
+// get the queries of the model.
+type ModelWithGeneratedColumns interface {
+	Model
+	GeneratedColumns() []string
+}
+
+// generatedColumns returns the generated columns of the model, if any.
+func generatedColumns(m any) []string {
+	if g, ok := m.(ModelWithGeneratedColumns); ok {
+		return g.GeneratedColumns()
+	}
+	return nil
+}
+
+// Validator is the interface implemented by a model that validates itself
+// before being inserted or updated. If Validate returns an error, the write is
+// aborted and the error is returned without hitting the database.
+type Validator interface {
+	Validate() error
+}
+
+// validate calls the Validate method if the model implements Validator.
+func validate(m Model) error {
+	if v, ok := m.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
 type Base struct {
 	ID        string       `db:"id"`
 	CreatedAt time.Time    `db:"created_at"`
@@ -59,3 +101,250 @@ func Queries(builder *qb.QueryBuilder) (selectQ, insertQ, updateQ, deleteQ strin
 	deleteQ = builder.Delete()
 	return
 }
+
+// QueriesWithGenerated returns the same queries as Queries, but the insert and
+// update queries do not include the given generated columns.
+func QueriesWithGenerated(builder *qb.QueryBuilder, generated ...string) (selectQ, insertQ, updateQ, deleteQ string) {
+	wb := writeBuilder(builder, generated)
+	selectQ = builder.Select()
+	insertQ = wb.NamedInsertWithReturning()
+	updateQ = wb.NamedUpdate()
+	deleteQ = builder.Delete()
+	return
+}
+
+// writeBuilder returns a copy of the query builder without the given generated
+// columns. If there are no generated columns, it returns the same builder.
+func writeBuilder(b *qb.QueryBuilder, generated []string) *qb.QueryBuilder {
+	if len(generated) == 0 {
+		return b
+	}
+	wb := *b
+	wb.Columns = slices.DeleteFunc(slices.Clone(b.Columns), func(c string) bool {
+		return slices.Contains(generated, c)
+	})
+	return &wb
+}
+
+var queryBuilders sync.Map
+
+// queryBuilder returns the query builder for the given model, using `?` as the
+// binding parameter. Query builders are cached by type and must not be
+// modified.
+//
+// The table of a model without a dbtable tag is taken from the query returned
+// by its Select method, so the queries generated by the DB methods use the
+// same table as the queries of the model, however they were built.
+func queryBuilder(m any) (*qb.QueryBuilder, error) {
+	typ := reflect.TypeOf(m)
+	if v, ok := queryBuilders.Load(typ); ok {
+		return v.(*qb.QueryBuilder), nil
+	}
+	opts := []qb.Option{qb.BindType(qb.QUESTION)}
+	if _, ok := untaggedStructName(m); ok {
+		if table, ok := selectTable(m); ok {
+			opts = append(opts, qb.TableName(table))
+		}
+	}
+	b, err := qb.New(m, opts...)
+	if err != nil {
+		return nil, err
+	}
+	queryBuilders.Store(typ, b)
+	return b, nil
+}
+
+var selectTableRegexp = regexp.MustCompile(`(?i)\sFROM\s+([A-Za-z_][A-Za-z0-9_$]*(?:\.[A-Za-z_][A-Za-z0-9_$]*)?)`)
+
+// selectTable returns the table in the FROM clause of the query returned by the
+// Select method of the given model.
+func selectTable(m any) (string, bool) {
+	model, ok := m.(Model)
+	if !ok {
+		return "", false
+	}
+	match := selectTableRegexp.FindStringSubmatch(model.Select())
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// NewBuilder returns the query builder of the given model, like qb.New, but
+// the table name of a model without a dbtable tag is derived from its struct
+// name with the given naming function, for example, PluralTableName. If naming
+// is nil, or a qb.TableName option is given, it behaves like qb.New.
+//
+//	var userSelectQ, userInsertQ, userUpdateQ, userDeleteQ = sequel.Queries(
+//		sequel.MustBuilder(&User{}, sequel.PluralTableName),
+//	)
+//
+// The DB methods that generate their own queries, like SelectByIDs or Upsert,
+// use the table in the query returned by the Select method of these models.
+func NewBuilder(m any, naming func(structName string) string, opts ...qb.Option) (*qb.QueryBuilder, error) {
+	if naming != nil {
+		if name, ok := untaggedStructName(m); ok {
+			opts = append([]qb.Option{qb.TableName(naming(name))}, opts...)
+		}
+	}
+	return qb.New(m, opts...)
+}
+
+// MustBuilder is like NewBuilder but panics if the query builder cannot be
+// created.
+func MustBuilder(m any, naming func(structName string) string, opts ...qb.Option) *qb.QueryBuilder {
+	b, err := NewBuilder(m, naming, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// untaggedStructName returns the name of the struct type of m if none of its
+// fields has a dbtable tag.
+func untaggedStructName(m any) (string, bool) {
+	typ := reflect.TypeOf(m)
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return "", false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if tag := typ.Field(i).Tag.Get("dbtable"); tag != "" && tag != "-" {
+			return "", false
+		}
+	}
+	return typ.Name(), true
+}
+
+// PluralTableName returns the given struct name in snake_case and pluralized
+// with the regular English rules, e.g. user_groups for UserGroup, categories
+// for Category or addresses for Address. Irregular plurals, like people, are
+// not supported, use a dbtable tag for them.
+func PluralTableName(structName string) string {
+	var b strings.Builder
+	for i, r := range structName {
+		if unicode.IsUpper(r) && i != 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	name := b.String()
+
+	switch {
+	case name == "":
+		return ""
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsRune("aeiou_", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	default:
+		return name + "s"
+	}
+}
+
+// hasColumn returns true if the given query builder contains the column.
+func hasColumn(b *qb.QueryBuilder, column string) bool {
+	return slices.Contains(b.Columns, column)
+}
+
+// TableName returns the name of the table of the given model, the value of the
+// dbtable tag in the model struct, usually set on the embedded Base. If the tag
+// is not set, the name is the table in the query returned by the Select method
+// of the model, see [NewBuilder], or the struct name in snake_case, e.g.
+// user_group for UserGroup.
+func TableName(m Model) (string, error) {
+	b, err := queryBuilder(m)
+	if err != nil {
+		return "", err
+	}
+	return b.Table, nil
+}
+
+// DumpQueries returns the queries that the given model uses, keyed by the
+// operation: "select", "insert", "update", "delete", and "hardDelete" if the
+// model implements ModelWithHardDelete. It is useful for debugging and to
+// verify that the queries generated match the expected ones.
+func DumpQueries(m Model) map[string]string {
+	queries := map[string]string{
+		"select": m.Select(),
+		"insert": m.Insert(),
+		"update": m.Update(),
+		"delete": m.Delete(),
+	}
+	if hd, ok := m.(ModelWithHardDelete); ok {
+		queries["hardDelete"] = hd.HardDelete()
+	}
+	return queries
+}
+
+// SchemaError is the error returned by ValidateModel when the columns of a
+// model are not found in its table.
+type SchemaError struct {
+	Table          string
+	MissingColumns []string
+}
+
+// Error implements the error interface on the SchemaError.
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("table %s does not have the columns %s", e.Table, strings.Join(e.MissingColumns, ", "))
+}
+
+// CompareModel compares the columns of the given model, from its db tags, with
+// the columns of its table in the database. It returns the model columns that
+// are missing in the table, and the table columns that are not used by the
+// model. It fails if the table does not exist.
+func (d *DB) CompareModel(ctx context.Context, m Model) (missing, extra []string, err error) {
+	b, err := queryBuilder(m)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var schema sql.NullString
+	table := b.Table
+	if i := strings.IndexByte(table, '.'); i >= 0 {
+		schema = sql.NullString{String: table[:i], Valid: true}
+		table = table[i+1:]
+	}
+
+	var columns []string
+	if err := d.GetAll(ctx, &columns, `SELECT column_name FROM information_schema.columns
+		WHERE table_schema = COALESCE($1, current_schema()) AND table_name = $2
+		ORDER BY ordinal_position`, schema, table); err != nil {
+		return nil, nil, err
+	}
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("table %s not found", b.Table)
+	}
+
+	for _, c := range b.Columns {
+		if !slices.Contains(columns, c) {
+			missing = append(missing, c)
+		}
+	}
+	for _, c := range columns {
+		if !hasColumn(b, c) {
+			extra = append(extra, c)
+		}
+	}
+	return missing, extra, nil
+}
+
+// ValidateModel verifies that all the columns of the given model, from its db
+// tags, exist in its table. If any column is missing, it returns a
+// [*SchemaError]. Table columns not used by the model are allowed; use
+// CompareModel to get them. It is useful in tests to detect differences
+// between the models and the database schema.
+func (d *DB) ValidateModel(ctx context.Context, m Model) error {
+	missing, _, err := d.CompareModel(ctx, m)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		b, _ := queryBuilder(m)
+		return &SchemaError{Table: b.Table, MissingColumns: missing}
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/qb"
+)
+
+func TestDumpQueries(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Model
+		want map[string]string
+	}{
+		{"ok", &personModel{}, map[string]string{
+			"select": "SELECT id, created_at, updated_at, deleted_at, name, email FROM person_test WHERE id = $1 AND deleted_at IS NULL",
+			"insert": personInsertQ,
+			"update": personUpdateQ,
+			"delete": personDeleteQ,
+		}},
+		{"ok with hard delete", &personModelExtra{}, map[string]string{
+			"select":     personSelectQ,
+			"insert":     personInsertExecQ,
+			"update":     personUpdateQ,
+			"delete":     personDeleteQ,
+			"hardDelete": personHardDeleteQ,
+		}},
+		{"ok binded", &personModelBinded{}, map[string]string{
+			"select":     "SELECT id, created_at, updated_at, deleted_at, name, email FROM person_test WHERE id = ? AND deleted_at IS NULL",
+			"insert":     personBindedInsertQ,
+			"update":     personBindedUpdateQ,
+			"delete":     "UPDATE person_test SET deleted_at = ? WHERE id = ?",
+			"hardDelete": "DELETE FROM person_test WHERE id = ?",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DumpQueries(tt.m))
+		})
+	}
+}
+
+type typesModelNoTag struct {
+	Base
+	Email CIText `db:"email"`
+}
+
+func (m *typesModelNoTag) Select() string { return "" }
+func (m *typesModelNoTag) Insert() string { return "" }
+func (m *typesModelNoTag) Update() string { return "" }
+func (m *typesModelNoTag) Delete() string { return "" }
+
+func TestTableName(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Model
+		want string
+	}{
+		{"ok", &personModel{}, "person_test"},
+		{"ok embedded", &personModelBinded{}, "person_test"},
+		{"ok with schema", &personModelPartial{}, "public.person_test"},
+		{"ok types", &typesModel{}, "types_test"},
+		{"ok without tag", &typesModelNoTag{}, "types_model_no_tag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TableName(tt.m)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPluralTableName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"User", "users"},
+		{"UserGroup", "user_groups"},
+		{"Category", "categories"},
+		{"Key", "keys"},
+		{"Address", "addresses"},
+		{"Box", "boxes"},
+		{"Batch", "batches"},
+		{"Wish", "wishes"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, PluralTableName(tt.name), tt.name)
+	}
+}
+
+var (
+	namingCategorySelectQ, namingCategoryInsertQ, namingCategoryUpdateQ, namingCategoryDeleteQ = Queries(MustBuilder(&namingCategory{}, PluralTableName))
+	namingItemSelectQ, namingItemInsertQ, namingItemUpdateQ, namingItemDeleteQ                 = Queries(qb.Must(&namingItem{}))
+)
+
+type namingCategory struct {
+	Base
+	Name string `db:"name"`
+}
+
+func (m *namingCategory) Select() string { return namingCategorySelectQ }
+func (m *namingCategory) Insert() string { return namingCategoryInsertQ }
+func (m *namingCategory) Update() string { return namingCategoryUpdateQ }
+func (m *namingCategory) Delete() string { return namingCategoryDeleteQ }
+
+type namingItem struct {
+	Base
+	Name string `db:"name"`
+}
+
+func (m *namingItem) Select() string { return namingItemSelectQ }
+func (m *namingItem) Insert() string { return namingItemInsertQ }
+func (m *namingItem) Update() string { return namingItemUpdateQ }
+func (m *namingItem) Delete() string { return namingItemDeleteQ }
+
+type namingTagged struct {
+	Base `dbtable:"tagged_test"`
+	Name string `db:"name"`
+}
+
+func TestNewBuilder(t *testing.T) {
+	b, err := NewBuilder(&namingCategory{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "naming_category", b.Table)
+
+	b, err = NewBuilder(&namingCategory{}, PluralTableName)
+	require.NoError(t, err)
+	assert.Equal(t, "naming_categories", b.Table)
+	assert.Equal(t, "SELECT id, created_at, updated_at, deleted_at, name FROM naming_categories WHERE id = $1 AND deleted_at IS NULL", b.Select())
+
+	b = MustBuilder(namingCategory{}, PluralTableName, qb.BindType(qb.QUESTION))
+	assert.Equal(t, "naming_categories", b.Table)
+	b = MustBuilder(&namingCategory{}, PluralTableName, qb.TableName("categories"))
+	assert.Equal(t, "categories", b.Table)
+	b = MustBuilder(&namingTagged{}, PluralTableName)
+	assert.Equal(t, "tagged_test", b.Table)
+
+	_, err = NewBuilder("foo", PluralTableName)
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		MustBuilder("foo", PluralTableName)
+	})
+}
+
+func Test_queryBuilder_untagged(t *testing.T) {
+	// The generated queries use the table of the model queries.
+	tests := []struct {
+		name string
+		m    Model
+		want string
+	}{
+		{"plural", &namingCategory{}, "naming_categories"},
+		{"singular", &namingItem{}, "naming_item"},
+		{"tagged", &personModel{}, "person_test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := queryBuilder(tt.m)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, b.Table)
+			assert.Contains(t, tt.m.Select(), tt.want)
+		})
+	}
+}
+
+var generatedSelectQ, generatedInsertQ, generatedUpdateQ, generatedDeleteQ string
+
+func init() {
+	builder := qb.Must(&generatedModel{})
+	generatedSelectQ, generatedInsertQ, generatedUpdateQ, generatedDeleteQ = QueriesWithGenerated(builder, "total_cents")
+}
+
+type generatedModel struct {
+	Base       `dbtable:"generated_test"`
+	SKU        string `db:"sku"`
+	PriceCents int    `db:"price_cents"`
+	Quantity   int    `db:"quantity"`
+	TotalCents int    `db:"total_cents"`
+}
+
+func (m *generatedModel) Select() string             { return generatedSelectQ }
+func (m *generatedModel) Insert() string             { return generatedInsertQ }
+func (m *generatedModel) Update() string             { return generatedUpdateQ }
+func (m *generatedModel) Delete() string             { return generatedDeleteQ }
+func (m *generatedModel) GeneratedColumns() []string { return []string{"total_cents"} }
+
+func TestQueriesWithGenerated(t *testing.T) {
+	assert.Equal(t, "SELECT id, created_at, updated_at, deleted_at, sku, price_cents, quantity, total_cents FROM generated_test WHERE id = $1 AND deleted_at IS NULL", generatedSelectQ)
+	assert.Equal(t, "INSERT INTO generated_test (created_at, updated_at, deleted_at, sku, price_cents, quantity) VALUES (:created_at, :updated_at, :deleted_at, :sku, :price_cents, :quantity) RETURNING id", generatedInsertQ)
+	assert.Equal(t, "UPDATE generated_test SET updated_at = :updated_at, deleted_at = :deleted_at, sku = :sku, price_cents = :price_cents, quantity = :quantity WHERE id = :id", generatedUpdateQ)
+	assert.Equal(t, "UPDATE generated_test SET deleted_at = $1 WHERE id = $2", generatedDeleteQ)
+
+	// The cached query builder is not modified
+	b, err := queryBuilder(&generatedModel{})
+	require.NoError(t, err)
+	assert.Contains(t, b.Columns, "total_cents")
+}
+
+func TestDB_generatedColumns(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM generated_test")
+		assert.NoError(t, err)
+	})
+
+	m := &generatedModel{SKU: "revolver", PriceCents: 1500, Quantity: 2}
+	require.NoError(t, db.Insert(ctx, m))
+
+	var got generatedModel
+	assert.NoError(t, db.Select(ctx, &got, m.GetID()))
+	assert.Equal(t, 3000, got.TotalCents)
+
+	got.Quantity = 3
+	assert.NoError(t, db.Update(ctx, &got))
+	assert.NoError(t, db.Reload(ctx, &got))
+	assert.Equal(t, 4500, got.TotalCents)
+
+	assert.NoError(t, db.UpdateMap(ctx, &got, map[string]any{"price_cents": 1000}))
+	assert.NoError(t, db.Reload(ctx, &got))
+	assert.Equal(t, 3000, got.TotalCents)
+	assert.Error(t, db.UpdateMap(ctx, &got, map[string]any{"total_cents": 1}))
+
+	up := &generatedModel{SKU: "revolver", PriceCents: 2000, Quantity: 1}
+	assert.NoError(t, db.Upsert(ctx, up, "sku"))
+	assert.Equal(t, m.GetID(), up.GetID())
+	assert.Equal(t, 2000, up.TotalCents)
+}
+
+var personNestedSelectQ, personNestedInsertQ, personNestedUpdateQ, personNestedDeleteQ string
+
+func init() {
+	builder := qb.Must(&personModelNested{})
+	personNestedSelectQ, personNestedInsertQ, personNestedUpdateQ, personNestedDeleteQ = Queries(builder)
+}
+
+type contactMixin struct {
+	Email sql.NullString `db:"email"`
+}
+
+type personMixin struct {
+	Name string `db:"name"`
+	contactMixin
+}
+
+// personModelNested is a person_test model with columns in two levels of
+// embedded structs.
+type personModelNested struct {
+	Base `dbtable:"person_test"`
+	personMixin
+}
+
+func (m *personModelNested) Select() string { return personNestedSelectQ }
+func (m *personModelNested) Insert() string { return personNestedInsertQ }
+func (m *personModelNested) Update() string { return personNestedUpdateQ }
+func (m *personModelNested) Delete() string { return personNestedDeleteQ }
+
+func TestDB_nestedEmbedding(t *testing.T) {
+	assert.Equal(t, personSelectQ, personNestedSelectQ)
+	assert.Equal(t, personInsertQ, personNestedInsertQ)
+	assert.Equal(t, personUpdateQ, personNestedUpdateQ)
+
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	m := &personModelNested{personMixin: personMixin{
+		Name:         "Lucky Luke",
+		contactMixin: contactMixin{Email: NullString("lucky@example.com")},
+	}}
+	require.NoError(t, db.Insert(ctx, m))
+
+	var got personModelNested
+	assert.NoError(t, db.Select(ctx, &got, m.GetID()))
+	assert.Equal(t, "Lucky Luke", got.Name)
+	assert.Equal(t, NullString("lucky@example.com"), got.Email)
+
+	got.Email = NullString("luke@example.com")
+	assert.NoError(t, db.Update(ctx, &got))
+	var p personModel
+	assert.NoError(t, db.Select(ctx, &p, m.GetID()))
+	assert.Equal(t, NullString("luke@example.com"), p.Email)
+
+	assert.NoError(t, db.UpdateMap(ctx, &got, map[string]any{"email": "lucky@example.com"}))
+	assert.NoError(t, db.Reload(ctx, &got))
+	assert.Equal(t, NullString("lucky@example.com"), got.Email)
+}
+
+type personModelMissing struct {
+	personModel
+	Phone   string `db:"phone"`
+	Address string `db:"address"`
+}
+
+type personModelPartial struct {
+	Base `dbtable:"public.person_test"`
+	Name string `db:"name"`
+}
+
+func (m *personModelPartial) Select() string { return "" }
+func (m *personModelPartial) Insert() string { return "" }
+func (m *personModelPartial) Update() string { return "" }
+func (m *personModelPartial) Delete() string { return "" }
+
+type missingTableModel struct {
+	Base `dbtable:"missing_table"`
+}
+
+func (m *missingTableModel) Select() string { return "" }
+func (m *missingTableModel) Insert() string { return "" }
+func (m *missingTableModel) Update() string { return "" }
+func (m *missingTableModel) Delete() string { return "" }
+
+func TestDB_ValidateModel(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+
+	t.Run("ok", func(t *testing.T) {
+		assert.NoError(t, db.ValidateModel(ctx, &personModel{}))
+		assert.NoError(t, db.ValidateModel(ctx, &typesModel{}))
+		assert.NoError(t, db.ValidateModel(ctx, &personModelPartial{}))
+
+		missing, extra, err := db.CompareModel(ctx, &personModel{})
+		assert.NoError(t, err)
+		assert.Empty(t, missing)
+		assert.Empty(t, extra)
+	})
+
+	t.Run("ok extra", func(t *testing.T) {
+		missing, extra, err := db.CompareModel(ctx, &personModelPartial{})
+		assert.NoError(t, err)
+		assert.Empty(t, missing)
+		assert.Equal(t, []string{"email"}, extra)
+	})
+
+	t.Run("fail missing", func(t *testing.T) {
+		err := db.ValidateModel(ctx, &personModelMissing{})
+		assert.EqualError(t, err, "table person_test does not have the columns phone, address")
+		var serr *SchemaError
+		if assert.ErrorAs(t, err, &serr) {
+			assert.Equal(t, &SchemaError{Table: "person_test", MissingColumns: []string{"phone", "address"}}, serr)
+		}
+	})
+
+	t.Run("fail table", func(t *testing.T) {
+		assert.EqualError(t, db.ValidateModel(ctx, &missingTableModel{}), "table missing_table not found")
+	})
+}
@@ -0,0 +1,101 @@
+package sequel
+
+import (
+	"fmt"
+	"strings"
+
+	"go.step.sm/qb"
+)
+
+type orderTerm struct {
+	column string
+	desc   bool
+}
+
+// Order is the sort order of a select, a list of columns sorted ascending or
+// descending, built with Asc and Desc:
+//
+//	order := Asc("created_at").Desc("name")
+//
+// Unlike an ORDER BY string, the columns are validated against the db tags of
+// the model when the order is used, so it can be safely built from client
+// input, see ParseOrder. The zero Order does not sort the rows.
+type Order struct {
+	terms []orderTerm
+}
+
+// Asc returns an Order sorting by the given column in ascending order.
+func Asc(column string) Order {
+	return Order{}.Asc(column)
+}
+
+// Desc returns an Order sorting by the given column in descending order.
+func Desc(column string) Order {
+	return Order{}.Desc(column)
+}
+
+// Asc returns a copy of the order that also sorts by the given column in
+// ascending order.
+func (o Order) Asc(column string) Order {
+	return o.add(column, false)
+}
+
+// Desc returns a copy of the order that also sorts by the given column in
+// descending order.
+func (o Order) Desc(column string) Order {
+	return o.add(column, true)
+}
+
+func (o Order) add(column string, desc bool) Order {
+	terms := make([]orderTerm, len(o.terms), len(o.terms)+1)
+	copy(terms, o.terms)
+	return Order{terms: append(terms, orderTerm{column: column, desc: desc})}
+}
+
+// IsZero returns true if the order does not have any column.
+func (o Order) IsZero() bool {
+	return len(o.terms) == 0
+}
+
+// String returns the order as an ORDER BY expression, e.g. "created_at, name
+// DESC". The columns are not validated.
+func (o Order) String() string {
+	s := make([]string, len(o.terms))
+	for i, t := range o.terms {
+		s[i] = t.column
+		if t.desc {
+			s[i] += " DESC"
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
+// ParseOrder parses a comma-separated list of columns, each one optionally
+// prefixed by `-` to sort in descending order, like "-created_at,name", the
+// usual format of a sort query parameter. An empty string returns the zero
+// Order. The column names are validated when the order is used.
+func ParseOrder(s string) (Order, error) {
+	var o Order
+	if s == "" {
+		return o, nil
+	}
+	for _, c := range strings.Split(s, ",") {
+		column, desc := strings.CutPrefix(strings.TrimSpace(c), "-")
+		if column == "" {
+			return Order{}, fmt.Errorf("invalid order %q", s)
+		}
+		o = o.add(column, desc)
+	}
+	return o, nil
+}
+
+// orderBy returns the ORDER BY expression of the order after checking that all
+// the columns are columns of the table of the query builder.
+func (o Order) orderBy(b *qb.QueryBuilder) (string, error) {
+	for _, t := range o.terms {
+		if !hasColumn(b, t.column) {
+			return "", fmt.Errorf("column %q not found in %s", t.column, b.Table)
+		}
+	}
+	return o.String(), nil
+}
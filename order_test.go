@@ -0,0 +1,69 @@
+package sequel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrder(t *testing.T) {
+	base := Asc("created_at")
+	o1 := base.Desc("name")
+	o2 := base.Asc("email")
+	assert.Equal(t, "created_at", base.String())
+	assert.Equal(t, "created_at, name DESC", o1.String())
+	assert.Equal(t, "created_at, email", o2.String())
+	assert.Equal(t, "name DESC, id", Desc("name").Asc("id").String())
+
+	assert.True(t, Order{}.IsZero())
+	assert.Empty(t, Order{}.String())
+	assert.False(t, base.IsZero())
+}
+
+func TestParseOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		s         string
+		want      Order
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok", "-created_at,name", Desc("created_at").Asc("name"), assert.NoError},
+		{"ok spaces", " name , -email ", Asc("name").Desc("email"), assert.NoError},
+		{"ok empty", "", Order{}, assert.NoError},
+		{"fail empty column", "name,,email", Order{}, assert.Error},
+		{"fail only dash", "-", Order{}, assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOrder(tt.s)
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOrder_orderBy(t *testing.T) {
+	b, err := queryBuilder(&personModel{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		order     Order
+		want      string
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok", Desc("created_at").Asc("name"), "created_at DESC, name", assert.NoError},
+		{"ok zero", Order{}, "", assert.NoError},
+		{"fail missing column", Asc("name").Asc("phone"), "", assert.Error},
+		{"fail injection", Asc("name; DROP TABLE person_test"), "", assert.Error},
+		{"fail expression", Asc("lower(name)"), "", assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.order.orderBy(b)
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
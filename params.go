@@ -0,0 +1,140 @@
+package sequel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// replaceParams returns the query with each positional parameter, $1, $2, ...,
+// replaced with the string returned by fn for its number. The parameters in
+// string literals, quoted identifiers, dollar-quoted strings, like $$...$$ or
+// $tag$...$tag$, and comments are left untouched. It fails if fn fails, or if
+// a string, identifier, or block comment is not terminated.
+func replaceParams(query string, fn func(n int) (string, error)) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			j := closingQuote(query, i+1, c)
+			if j < 0 {
+				return "", fmt.Errorf("unterminated quote %c", c)
+			}
+			sb.WriteString(query[i:j])
+			i = j - 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+			sb.WriteString(query[i : i+j])
+			i += j - 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			j := closingComment(query, i+2)
+			if j < 0 {
+				return "", fmt.Errorf("unterminated comment")
+			}
+			sb.WriteString(query[i:j])
+			i = j - 1
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+			n, err := strconv.Atoi(query[i+1 : j])
+			if err != nil {
+				return "", fmt.Errorf("invalid parameter %s", query[i:j])
+			}
+			s, err := fn(n)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(s)
+			i = j - 1
+		case c == '$' && (i == 0 || !isIdentifierChar(query[i-1])):
+			tag, ok := dollarQuoteTag(query, i)
+			if !ok {
+				sb.WriteByte(c)
+				continue
+			}
+			j := strings.Index(query[i+len(tag):], tag)
+			if j < 0 {
+				return "", fmt.Errorf("unterminated dollar-quoted string %s", tag)
+			}
+			j += i + 2*len(tag)
+			sb.WriteString(query[i:j])
+			i = j - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
+
+// closingQuote returns the index after the quote that closes the string
+// starting at i, or -1 if it is not closed. Doubled quotes are part of the
+// string.
+func closingQuote(query string, i int, quote byte) int {
+	for i < len(query) {
+		if query[i] == quote {
+			if i+1 < len(query) && query[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return -1
+}
+
+// closingComment returns the index after the `*/` that closes the block
+// comment starting at i, or -1 if it is not closed. As in PostgreSQL, block
+// comments can be nested.
+func closingComment(query string, i int) int {
+	depth := 1
+	for i+1 < len(query) {
+		switch {
+		case query[i] == '/' && query[i+1] == '*':
+			depth++
+			i += 2
+		case query[i] == '*' && query[i+1] == '/':
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// dollarQuoteTag returns the delimiter of the dollar-quoted string starting at
+// i, `$$` or `$tag$`, and true, or false if there is no delimiter at i.
+func dollarQuoteTag(query string, i int) (string, bool) {
+	j := i + 1
+	for j < len(query) && query[j] != '$' {
+		c := query[j]
+		if !isIdentifierChar(c) || (j == i+1 && isDigit(c)) {
+			return "", false
+		}
+		j++
+	}
+	if j == len(query) {
+		return "", false
+	}
+	return query[i : j+1], true
+}
+
+// isIdentifierChar returns true if c can be part of an unquoted identifier.
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,43 @@
+package sequel
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_replaceParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		want      string
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok", "SELECT $1, $2 + $10", "SELECT <1>, <2> + <10>", assert.NoError},
+		{"ok quotes", `SELECT 'it''s $1', "$1", $1`, `SELECT 'it''s $1', "$1", <1>`, assert.NoError},
+		{"ok line comment", "SELECT $1 -- $2\n, $3 -- $4", "SELECT <1> -- $2\n, <3> -- $4", assert.NoError},
+		{"ok block comment", "SELECT /* $1 */ $2 /* a /* $3 */ $4 */ $5", "SELECT /* $1 */ <2> /* a /* $3 */ $4 */ <5>", assert.NoError},
+		{"ok dollar quotes", "SELECT $$ $1 $$, $2", "SELECT $$ $1 $$, <2>", assert.NoError},
+		{"ok tagged dollar quotes", "DO $body$ BEGIN PERFORM $1, $$ $2 $$; END $body$; SELECT $3", "DO $body$ BEGIN PERFORM $1, $$ $2 $$; END $body$; SELECT <3>", assert.NoError},
+		{"ok identifier with dollar", "SELECT a$b$, $1", "SELECT a$b$, <1>", assert.NoError},
+		{"ok lone dollar", "SELECT $ $1", "SELECT $ <1>", assert.NoError},
+		{"fail quote", "SELECT 'foo $1", "", assert.Error},
+		{"fail identifier", `SELECT "foo $1`, "", assert.Error},
+		{"fail block comment", "SELECT /* /* */ $1", "", assert.Error},
+		{"fail dollar quotes", "SELECT $tag$ $1 $$", "", assert.Error},
+		{"fail fn", "SELECT $0", "", assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := replaceParams(tt.query, func(n int) (string, error) {
+				if n == 0 {
+					return "", assert.AnError
+				}
+				return "<" + strconv.Itoa(n) + ">", nil
+			})
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package sequel
+
+import (
+	"context"
+	"time"
+)
+
+// replicaLagQuery returns the time since the last transaction replayed by a
+// standby server, in seconds, or 0 if the server is not a standby or it has
+// replayed all the WAL it received.
+const replicaLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+
+// ReplicaLag returns the replication lag of the database, the time since the
+// last transaction replayed by the standby server, with microsecond precision.
+// It returns 0 if the database is not a standby, or if it has replayed all the
+// changes it received from the primary, as the time since the last replayed
+// transaction grows even if there are no new ones.
+func (d *DB) ReplicaLag(ctx context.Context) (time.Duration, error) {
+	var seconds float64
+	if err := d.Get(ctx, &seconds, replicaLagQuery); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)).Round(time.Microsecond), nil
+}
+
+// ReplicaOrPrimary returns the replica if its replication lag, see ReplicaLag,
+// is at most maxLag, and the primary otherwise, or if the lag cannot be
+// checked. It allows to route most reads to a replica, while keeping the ones
+// that need to see recent writes on the primary:
+//
+//	db := sequel.ReplicaOrPrimary(ctx, replica, primary, time.Second)
+//	err := db.Select(ctx, &user, id)
+//
+// The lag is checked on each call, with a query to the replica.
+func ReplicaOrPrimary(ctx context.Context, replica, primary *DB, maxLag time.Duration) *DB {
+	lag, err := replica.ReplicaLag(ctx)
+	if err != nil || lag > maxLag {
+		return primary
+	}
+	return replica
+}
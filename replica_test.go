@@ -0,0 +1,51 @@
+package sequel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_ReplicaLag(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+
+	t.Run("ok primary", func(t *testing.T) {
+		lag, err := db.ReplicaLag(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), lag)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := db.ReplicaLag(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestReplicaOrPrimary(t *testing.T) {
+	primary, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, primary.Close())
+	})
+	replica, err := New(postgresDataSource, WithReadOnly())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	assert.Same(t, replica, ReplicaOrPrimary(ctx, replica, primary, time.Second))
+	assert.Same(t, replica, ReplicaOrPrimary(ctx, replica, primary, 0))
+
+	// The lag cannot be checked.
+	require.NoError(t, replica.Close())
+	assert.Same(t, primary, ReplicaOrPrimary(ctx, replica, primary, time.Second))
+}
@@ -0,0 +1,91 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IsConnError returns true if the given error indicates a broken or closed
+// connection, like a connection reset, a failure to dial the server, or a
+// server shutdown error (57P01, 57P02, 57P03), or a connection exception
+// (class 08), or if pgconn reports that the query is safe to retry because it
+// was not sent. Queries failing with these errors can be retried on a new
+// connection if they are idempotent. Other network errors, like a read
+// timeout, are not connection errors, as the query might still be running.
+func IsConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if code := sqlState(err); code != "" {
+		switch code {
+		case "57P01", "57P02", "57P03":
+			return true
+		default:
+			return strings.HasPrefix(code, "08")
+		}
+	}
+
+	var netErr *net.OpError
+	switch {
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone):
+		return true
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return true
+	case errors.As(err, &netErr):
+		return netErr.Op == "dial"
+	case pgconn.SafeToRetry(err):
+		return true
+	default:
+		return false
+	}
+}
+
+// The delays between the retries of a query failing with a connection error.
+// The delay doubles on each retry, so a database that is restarting, or not
+// ready yet, is not flooded with connection attempts.
+const (
+	retryMinBackoff = 50 * time.Millisecond
+	retryMaxBackoff = 5 * time.Second
+)
+
+// retry calls fn and, if it fails with a connection error, calls it again up to
+// the number of retries set with WithRetryOnConnError while the context is not
+// done. The first retry is done after retryMinBackoff, and the delay doubles on
+// each retry up to retryMaxBackoff.
+func (d *DB) retry(ctx context.Context, fn func() error) error {
+	err := fn()
+	backoff := retryMinBackoff
+	for i := 0; i < d.connRetries && IsConnError(err); i++ {
+		if !sleepContext(ctx, backoff) {
+			break
+		}
+		err = fn()
+		backoff = min(2*backoff, retryMaxBackoff)
+	}
+	return err
+}
+
+// sleepContext waits for the given duration and returns true, or returns
+// false if the context is done before.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
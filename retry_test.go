@@ -0,0 +1,153 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// safeToRetryError is an error that pgconn reports as safe to retry.
+type safeToRetryError struct{}
+
+func (safeToRetryError) Error() string     { return "safe to retry" }
+func (safeToRetryError) SafeToRetry() bool { return true }
+
+func TestIsConnError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"true admin shutdown", &pgconn.PgError{Code: "57P01"}, true},
+		{"true crash shutdown", &pgconn.PgError{Code: "57P02"}, true},
+		{"true cannot connect now", &pgconn.PgError{Code: "57P03"}, true},
+		{"true connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"true bad conn", driver.ErrBadConn, true},
+		{"true conn done", sql.ErrConnDone, true},
+		{"true unexpected EOF", fmt.Errorf("failed to receive message: %w", io.ErrUnexpectedEOF), true},
+		{"true connection reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{"true broken pipe", fmt.Errorf("write failed: %w", syscall.EPIPE), true},
+		{"true dial", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("no route to host")}, true},
+		{"true safe to retry", fmt.Errorf("failed to send: %w", safeToRetryError{}), true},
+		{"false nil", nil, false},
+		{"false unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"false no rows", sql.ErrNoRows, false},
+		{"false canceled", context.Canceled, false},
+		{"false read timeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, false},
+		{"false other", errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsConnError(tt.err))
+		})
+	}
+}
+
+func TestDB_retry(t *testing.T) {
+	admin, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, admin.Close())
+	})
+
+	ctx := context.Background()
+
+	// terminate kills the only connection of the given database and waits
+	// until it is gone.
+	terminate := func(t *testing.T, db *DB) {
+		t.Helper()
+		var pid int
+		require.NoError(t, db.Get(ctx, &pid, "SELECT pg_backend_pid()"))
+		_, err := admin.Exec(ctx, "SELECT pg_terminate_backend($1)", pid)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			var n int
+			require.NoError(t, admin.Get(ctx, &n, "SELECT count(*) FROM pg_stat_activity WHERE pid = $1", pid))
+			return n == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		db, err := New(postgresDataSource, WithMaxOpenConnections(1), WithRetryOnConnError(1))
+		require.NoError(t, err)
+		defer db.Close()
+
+		terminate(t, db)
+		var n int
+		assert.NoError(t, db.Get(ctx, &n, "SELECT 1"))
+		assert.Equal(t, 1, n)
+
+		terminate(t, db)
+		var ns []int
+		assert.NoError(t, db.GetAll(ctx, &ns, "SELECT 1"))
+		assert.Equal(t, []int{1}, ns)
+	})
+
+	t.Run("ok counter", func(t *testing.T) {
+		db, err := New(postgresDataSource, WithRetryOnConnError(2))
+		require.NoError(t, err)
+		defer db.Close()
+
+		var calls int
+		err = db.retry(ctx, func() error {
+			calls++
+			return driver.ErrBadConn
+		})
+		assert.ErrorIs(t, err, driver.ErrBadConn)
+		assert.Equal(t, 3, calls)
+
+		calls = 0
+		err = db.retry(ctx, func() error {
+			calls++
+			return sql.ErrNoRows
+		})
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("ok backoff", func(t *testing.T) {
+		db, err := New(postgresDataSource, WithRetryOnConnError(3))
+		require.NoError(t, err)
+		defer db.Close()
+
+		var calls []time.Time
+		err = db.retry(ctx, func() error {
+			calls = append(calls, time.Now())
+			return driver.ErrBadConn
+		})
+		assert.ErrorIs(t, err, driver.ErrBadConn)
+		require.Len(t, calls, 4)
+		for i, want := range []time.Duration{retryMinBackoff, 2 * retryMinBackoff, 4 * retryMinBackoff} {
+			assert.GreaterOrEqual(t, calls[i+1].Sub(calls[i]), want)
+		}
+	})
+
+	t.Run("fail canceled", func(t *testing.T) {
+		db, err := New(postgresDataSource, WithRetryOnConnError(10))
+		require.NoError(t, err)
+		defer db.Close()
+
+		ctx, cancel := context.WithTimeout(ctx, retryMinBackoff/2)
+		defer cancel()
+
+		var calls int
+		err = db.retry(ctx, func() error {
+			calls++
+			return driver.ErrBadConn
+		})
+		assert.ErrorIs(t, err, driver.ErrBadConn)
+		assert.Equal(t, 1, calls)
+	})
+}
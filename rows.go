@@ -0,0 +1,83 @@
+package sequel
+
+import (
+	"context"
+
+	"github.com/go-sqlx/sqlx"
+)
+
+// Rows is an iterator over the rows of a query that scans each row into a new
+// value of type T, matching the columns by the db tags of T instead of by
+// position.
+//
+//	rows, err := QueryInto[User](ctx, db, "SELECT * FROM users WHERE active")
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//	for rows.Next() {
+//		u := rows.Value()
+//		// ...
+//	}
+//	if err := rows.Err(); err != nil {
+//		return err
+//	}
+type Rows[T any] struct {
+	rows  *sqlx.Rows
+	value *T
+	err   error
+}
+
+// QueryInto executes a query that returns rows, typically a SELECT, and
+// returns an iterator that scans each row into a new value of type T, a struct
+// with db tags like a model. The args are for any placeholder parameters in the
+// query.
+//
+// Unlike GetAll, the rows are not loaded into memory at once. The iterator
+// must be closed to release the connection.
+func QueryInto[T any](ctx context.Context, db *DB, query string, args ...any) (*Rows[T], error) {
+	rows, err := db.queryx(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Rows[T]{
+		rows: rows,
+	}, nil
+}
+
+// Next prepares the next row to be read with Value. It returns false if there
+// are no more rows or if scanning the row fails, the error can be checked with
+// Err.
+func (r *Rows[T]) Next() bool {
+	r.value = nil
+	if r.err != nil || !r.rows.Next() {
+		return false
+	}
+	v := new(T)
+	if err := r.rows.StructScan(v); err != nil {
+		r.err = err
+		return false
+	}
+	r.value = v
+	return true
+}
+
+// Value returns the current row. Each row is scanned into a new value, so the
+// value can be retained after calling Next.
+func (r *Rows[T]) Value() *T {
+	return r.value
+}
+
+// Err returns the error, if any, that was encountered during iteration.
+func (r *Rows[T]) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.rows.Err()
+}
+
+// Close closes the rows and releases the connection. It is safe to call Close
+// multiple times.
+func (r *Rows[T]) Close() error {
+	return r.rows.Close()
+}
@@ -0,0 +1,71 @@
+package sequel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryInto(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p2 := &personModel{Name: "Jolly Jumper", Email: NullString("jolly@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2}))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		rows, err := QueryInto[personModel](ctx, db, "SELECT * FROM person_test ORDER BY name")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var got []*personModel
+		for rows.Next() {
+			got = append(got, rows.Value())
+		}
+		assert.NoError(t, rows.Err())
+		assert.NoError(t, rows.Close())
+		assertEqualPersons(t, []*personModel{p2, p1}, got)
+		assert.Nil(t, rows.Value())
+	})
+
+	t.Run("ok projection", func(t *testing.T) {
+		type person struct {
+			Email string `db:"email"`
+			Name  string `db:"name"`
+		}
+		rows, err := QueryInto[person](ctx, db, "SELECT name, email FROM person_test WHERE id = $1", p1.GetID())
+		require.NoError(t, err)
+		defer rows.Close()
+
+		assert.True(t, rows.Next())
+		assert.Equal(t, &person{Name: "Lucky Luke", Email: "lucky@example.com"}, rows.Value())
+		assert.False(t, rows.Next())
+		assert.NoError(t, rows.Err())
+	})
+
+	t.Run("fail query", func(t *testing.T) {
+		_, err := QueryInto[personModel](ctx, db, "SELECT * FROM missing_table")
+		assert.Error(t, err)
+	})
+
+	t.Run("fail scan", func(t *testing.T) {
+		rows, err := QueryInto[personModel](ctx, db, "SELECT *, 1 AS missing FROM person_test")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		assert.False(t, rows.Next())
+		assert.Error(t, rows.Err())
+		assert.Nil(t, rows.Value())
+	})
+}
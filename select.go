@@ -0,0 +1,362 @@
+package sequel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.step.sm/qb"
+)
+
+// selectWhere returns a select query with the given condition on the table of
+// the query builder. Soft-deleted rows are filtered out unless the query
+// builder selects them.
+func selectWhere(b *qb.QueryBuilder, where string) string {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(b.Columns, ", "), b.Table, where)
+	if !b.SelectDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+	return query
+}
+
+// selectArray populates dest with the rows where the column and the given
+// array of values satisfy the condition in format, e.g. `%s @> ?`.
+func (d *DB) selectArray(ctx context.Context, dest any, m Model, column, format string, values any) error {
+	b, err := queryBuilder(m)
+	if err != nil {
+		return err
+	}
+	if !hasColumn(b, column) {
+		return fmt.Errorf("column %q not found in %s", column, b.Table)
+	}
+	query := selectWhere(b, fmt.Sprintf(format, column))
+	return d.GetAll(ctx, dest, d.Rebind(query), values)
+}
+
+// SelectArrayContains populates dest, a pointer to a slice, with the rows in the
+// table of the model m where the array column contains all the given values,
+// `column @> values`. Soft-deleted rows are not included.
+func SelectArrayContains[T any](ctx context.Context, db *DB, dest any, m Model, column string, values Array[T]) error {
+	return db.selectArray(ctx, dest, m, column, "%s @> ?", values)
+}
+
+// SelectArrayOverlaps populates dest, a pointer to a slice, with the rows in the
+// table of the model m where the array column has any element in common with
+// the given values, `column && values`. Soft-deleted rows are not included.
+func SelectArrayOverlaps[T any](ctx context.Context, db *DB, dest any, m Model, column string, values Array[T]) error {
+	return db.selectArray(ctx, dest, m, column, "%s && ?", values)
+}
+
+// SelectAnyOf populates dest, a pointer to a slice, with the rows in the table
+// of the model m where the column is equal to any of the given values, `column
+// = ANY(values)`. The values are sent as a single array parameter, so unlike
+// `IN (?, ?, ...)`, the query does not change with the number of values.
+// Soft-deleted rows are not included.
+func SelectAnyOf[T any](ctx context.Context, db *DB, dest any, m Model, column string, values Array[T]) error {
+	return db.selectArray(ctx, dest, m, column, "%s = ANY(?)", values)
+}
+
+// SelectByIDs returns the rows in the table of the model T with the given ids.
+// Soft-deleted rows are not included, and the order of the rows is not
+// guaranteed. If ids is empty, it returns an empty slice without querying the
+// database.
+//
+// The type parameter is the model struct, e.g. `SelectByIDs[User](ctx, db,
+// ids)` where *User implements Model.
+func SelectByIDs[T any, PT interface {
+	*T
+	Model
+}](ctx context.Context, db *DB, ids []string) ([]*T, error) {
+	if len(ids) == 0 {
+		return []*T{}, nil
+	}
+	b, err := queryBuilder(PT(new(T)))
+	if err != nil {
+		return nil, err
+	}
+	var dest []*T
+	query := selectWhere(b, b.PrimaryKey+" = ANY(?)")
+	if err := db.GetAll(ctx, &dest, db.Rebind(query), Array[string](ids)); err != nil {
+		return nil, err
+	}
+	return dest, nil
+}
+
+// SelectScoped populates the given model with the result of a select by id
+// query that also requires the columns in scope to be equal to the given
+// values, e.g. `map[string]any{"tenant_id": tenantID}`. It returns
+// sql.ErrNoRows if the row does not exist or does not belong to the scope, and
+// fails if any column in scope is not in the model. Soft-deleted rows are not
+// included.
+func (d *DB) SelectScoped(ctx context.Context, dest Model, id string, scope map[string]any) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "select", dest, "", "")
+	b, err := queryBuilder(dest)
+	if err != nil {
+		return err
+	}
+
+	columns := make([]string, 0, len(scope))
+	for column := range scope {
+		if !hasColumn(b, column) {
+			return fmt.Errorf("column %q not found in %s", column, b.Table)
+		}
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	where := b.PrimaryKey + " = ?"
+	args := []any{id}
+	for _, column := range columns {
+		where += " AND " + column + " = ?"
+		args = append(args, scope[column])
+	}
+	return d.Get(ctx, dest, d.Rebind(selectWhere(b, where)), args...)
+}
+
+// SelectLimit populates dest, a pointer to a slice, with a page of the rows in
+// the table of the model m matching the where condition, sorted by orderBy,
+// e.g. "created_at DESC, id". At most limit rows are returned, skipping the
+// first offset rows. Soft-deleted rows are not included. The where condition
+// can use `?` placeholders for the given args, and an empty condition selects
+// all the rows.
+//
+// Offset pagination gets slower as the offset grows, it is meant for small
+// tables like the ones in admin lists.
+func (d *DB) SelectLimit(ctx context.Context, dest any, m Model, where, orderBy string, limit, offset int, args ...any) error {
+	b, err := queryBuilder(m)
+	if err != nil {
+		return err
+	}
+	return d.selectLimit(ctx, dest, b, where, orderBy, limit, offset, args)
+}
+
+// SelectOrdered is like SelectLimit, but the rows are sorted by the given
+// Order, whose columns must be columns of the model. It is meant for lists
+// sorted by client input:
+//
+//	order, err := ParseOrder(r.URL.Query().Get("sort"))
+//	if err != nil {
+//		return err
+//	}
+//	err = db.SelectOrdered(ctx, &users, &User{}, "", order.Asc("id"), 20, 0)
+func (d *DB) SelectOrdered(ctx context.Context, dest any, m Model, where string, order Order, limit, offset int, args ...any) error {
+	b, err := queryBuilder(m)
+	if err != nil {
+		return err
+	}
+	orderBy, err := order.orderBy(b)
+	if err != nil {
+		return err
+	}
+	return d.selectLimit(ctx, dest, b, where, orderBy, limit, offset, args)
+}
+
+func (d *DB) selectLimit(ctx context.Context, dest any, b *qb.QueryBuilder, where, orderBy string, limit, offset int, args []any) error {
+	if limit <= 0 || offset < 0 {
+		return fmt.Errorf("invalid limit %d or offset %d", limit, offset)
+	}
+	if where == "" {
+		where = "true"
+	}
+
+	query := selectWhere(b, "("+where+")")
+	if orderBy != "" {
+		query += " ORDER BY " + orderBy
+	}
+	query += " LIMIT " + strconv.Itoa(limit)
+	if offset > 0 {
+		query += " OFFSET " + strconv.Itoa(offset)
+	}
+	return d.GetAll(ctx, dest, d.Rebind(query), args...)
+}
+
+// SearchText populates dest, a pointer to a slice, with the rows in the table of
+// the model m where the tsvector column matches the given text, `column @@
+// plainto_tsquery(query)`, sorted by their rank, the best matches first.
+// Soft-deleted rows are not included.
+//
+// The query is plain text, sent as a parameter, and it is converted to a
+// tsquery with the default text search configuration. The column does not
+// need to be a field of the model, as tsvector columns are rarely read, but it
+// must be a valid identifier.
+func (d *DB) SearchText(ctx context.Context, dest any, m Model, column, query string) error {
+	if !isIdentifier(column) {
+		return fmt.Errorf("invalid column name %q", column)
+	}
+	b, err := queryBuilder(m)
+	if err != nil {
+		return err
+	}
+	q := selectWhere(b, column+" @@ plainto_tsquery(?)") +
+		" ORDER BY ts_rank(" + column + ", plainto_tsquery(?)) DESC"
+	return d.GetAll(ctx, dest, d.Rebind(q), query, query)
+}
+
+type lockOptions struct {
+	strength   string
+	skipLocked bool
+	noWait     bool
+	orderBy    string
+	args       []any
+}
+
+// LockOption is the type of options that can be used to modify the queries that
+// lock rows.
+type LockOption func(*lockOptions)
+
+// ForNoKeyUpdate uses FOR NO KEY UPDATE as the locking clause instead of FOR
+// UPDATE. It is the lock taken by an UPDATE that does not modify a key column,
+// and unlike FOR UPDATE, it does not block the FOR KEY SHARE locks taken by the
+// foreign key checks of the rows referencing the locked ones.
+func ForNoKeyUpdate() LockOption {
+	return func(o *lockOptions) {
+		o.strength = "NO KEY UPDATE"
+	}
+}
+
+// ForShare uses FOR SHARE as the locking clause instead of FOR UPDATE. It is a
+// shared lock that prevents other transactions from updating or deleting the
+// rows, but not from reading them with FOR SHARE or FOR KEY SHARE.
+func ForShare() LockOption {
+	return func(o *lockOptions) {
+		o.strength = "SHARE"
+	}
+}
+
+// ForKeyShare uses FOR KEY SHARE as the locking clause instead of FOR UPDATE.
+// It is the weakest lock, the one taken by foreign key checks, and it only
+// prevents other transactions from deleting the rows or updating their key
+// columns.
+func ForKeyShare() LockOption {
+	return func(o *lockOptions) {
+		o.strength = "KEY SHARE"
+	}
+}
+
+// SkipLocked adds SKIP LOCKED to the locking clause, so rows locked by other
+// transactions are skipped instead of waiting for them.
+func SkipLocked() LockOption {
+	return func(o *lockOptions) {
+		o.skipLocked = true
+	}
+}
+
+// NoWait adds NOWAIT to the locking clause, so the query fails instead of
+// waiting if any selected row is locked by other transaction.
+func NoWait() LockOption {
+	return func(o *lockOptions) {
+		o.noWait = true
+	}
+}
+
+// LockOrderBy sets the ORDER BY expression of the query, e.g. "created_at" or
+// "priority DESC, created_at".
+func LockOrderBy(orderBy string) LockOption {
+	return func(o *lockOptions) {
+		o.orderBy = orderBy
+	}
+}
+
+// LockArgs sets the arguments for the `?` placeholders in the where condition.
+func LockArgs(args ...any) LockOption {
+	return func(o *lockOptions) {
+		o.args = args
+	}
+}
+
+// selectForUpdateWhere returns the query to select and lock the rows matching
+// where condition.
+func selectForUpdateWhere(b *qb.QueryBuilder, where string, limit int, o *lockOptions) (string, error) {
+	if o.skipLocked && o.noWait {
+		return "", errors.New("cannot use SkipLocked and NoWait at the same time")
+	}
+
+	if where == "" {
+		where = "true"
+	}
+
+	query := selectWhere(b, "("+where+")")
+	if o.orderBy != "" {
+		query += " ORDER BY " + o.orderBy
+	}
+	if limit > 0 {
+		query += " LIMIT " + strconv.Itoa(limit)
+	}
+	if o.strength == "" {
+		query += " FOR UPDATE"
+	} else {
+		query += " FOR " + o.strength
+	}
+	switch {
+	case o.skipLocked:
+		query += " SKIP LOCKED"
+	case o.noWait:
+		query += " NOWAIT"
+	}
+	return query, nil
+}
+
+// SelectForUpdateWhere populates dest, a pointer to a slice, with the rows in
+// the table of the model m matching the where condition, and locks them until
+// the end of the transaction. Soft-deleted rows are not included. An empty
+// where condition matches all the rows. If limit is greater than 0, at most
+// limit rows are returned.
+//
+// The rows are locked with FOR UPDATE, use the ForNoKeyUpdate, ForShare, or
+// ForKeyShare options to take a weaker lock.
+//
+// The where condition can use `?` placeholders, the arguments are passed with
+// the LockArgs option. For example, a worker pool can get a batch of jobs with:
+//
+//	var jobs []*Job
+//	err := tx.SelectForUpdateWhere(&jobs, &Job{}, "status = ?", 10,
+//		LockArgs("pending"), LockOrderBy("created_at"), SkipLocked())
+func (t *Tx) SelectForUpdateWhere(dest any, m Model, where string, limit int, opts ...LockOption) error {
+	b, err := queryBuilder(m)
+	if err != nil {
+		return err
+	}
+
+	o := new(lockOptions)
+	for _, fn := range opts {
+		fn(o)
+	}
+	query, err := selectForUpdateWhere(b, where, limit, o)
+	if err != nil {
+		return err
+	}
+	return t.tx.Select(dest, t.track(t.Rebind(query)), o.args...)
+}
+
+// NamedSelect runs the given query binding the named parameters with the
+// fields of arg, a struct or a map, and returns all the rows scanned into
+// values of type T.
+func NamedSelect[T any](ctx context.Context, db *DB, query string, arg any) ([]*T, error) {
+	query, args, err := db.db.BindNamed(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	var dest []*T
+	if err := db.GetAll(ctx, &dest, query, args...); err != nil {
+		return nil, err
+	}
+	return dest, nil
+}
+
+// NamedGetOne runs the given query binding the named parameters with the
+// fields of arg, a struct or a map, and returns the first row scanned into a
+// value of type T. If the query selects no rows, it returns sql.ErrNoRows.
+func NamedGetOne[T any](ctx context.Context, db *DB, query string, arg any) (*T, error) {
+	query, args, err := db.db.BindNamed(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	dest := new(T)
+	if err := db.Get(ctx, dest, query, args...); err != nil {
+		return nil, err
+	}
+	return dest, nil
+}
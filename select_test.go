@@ -0,0 +1,504 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectArray(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	m1 := &arrayModel{Integers: []int{1, 2, 3}, Texts: []string{"foo", "bar"}}
+	m2 := &arrayModel{Integers: []int{3, 4, 5}, Texts: []string{"bar", "zar"}}
+	m3 := &arrayModel{Integers: []int{5, 6, 7}, Texts: []string{"zar"}}
+	m4 := &arrayModel{Integers: []int{1, 2, 3}, Texts: []string{"foo", "bar"}}
+	require.NoError(t, db.InsertBatch(ctx, []Model{m1, m2, m3, m4}))
+	require.NoError(t, db.Delete(ctx, m4))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM array_test")
+		assert.NoError(t, err)
+	})
+
+	normalize := func(arrays []*arrayModel) []*arrayModel {
+		for _, m := range arrays {
+			m.CreatedAt = m.CreatedAt.UTC().Truncate(time.Second)
+			m.UpdatedAt = m.UpdatedAt.UTC().Truncate(time.Second)
+		}
+		return arrays
+	}
+	want := normalize([]*arrayModel{m1, m2, m3})
+
+	t.Run("contains", func(t *testing.T) {
+		var got []*arrayModel
+		assert.NoError(t, SelectArrayContains(ctx, db, &got, &arrayModel{}, "integers", Array[int]{3}))
+		assert.ElementsMatch(t, []*arrayModel{want[0], want[1]}, normalize(got))
+
+		assert.NoError(t, SelectArrayContains(ctx, db, &got, &arrayModel{}, "texts", Array[string]{"foo", "bar"}))
+		assert.ElementsMatch(t, []*arrayModel{want[0]}, normalize(got))
+
+		assert.NoError(t, SelectArrayContains(ctx, db, &got, &arrayModel{}, "texts", Array[string]{"foo", "zar"}))
+		assert.Empty(t, got)
+	})
+
+	t.Run("overlaps", func(t *testing.T) {
+		var got []*arrayModel
+		assert.NoError(t, SelectArrayOverlaps(ctx, db, &got, &arrayModel{}, "integers", Array[int]{1, 7}))
+		assert.ElementsMatch(t, []*arrayModel{want[0], want[2]}, normalize(got))
+
+		assert.NoError(t, SelectArrayOverlaps(ctx, db, &got, &arrayModel{}, "texts", Array[string]{"zar"}))
+		assert.ElementsMatch(t, []*arrayModel{want[1], want[2]}, normalize(got))
+
+		assert.NoError(t, SelectArrayOverlaps(ctx, db, &got, &arrayModel{}, "integers", Array[int]{100}))
+		assert.Empty(t, got)
+	})
+
+	t.Run("anyOf", func(t *testing.T) {
+		var got []*arrayModel
+		assert.NoError(t, SelectAnyOf(ctx, db, &got, &arrayModel{}, "id", Array[string]{m1.GetID(), m3.GetID(), m4.GetID()}))
+		assert.ElementsMatch(t, []*arrayModel{want[0], want[2]}, normalize(got))
+
+		assert.NoError(t, SelectAnyOf(ctx, db, &got, &arrayModel{}, "id", Array[string]{}))
+		assert.Empty(t, got)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var got []*arrayModel
+		assert.Error(t, SelectArrayContains(ctx, db, &got, &arrayModel{}, "missing", Array[int]{1}))
+		assert.Error(t, SelectAnyOf(ctx, db, &got, &arrayModel{}, "missing", Array[int]{1}))
+		assert.Error(t, SelectArrayOverlaps(ctx, db, &got, &arrayModel{}, "integers = integers OR true", Array[int]{1}))
+		assert.Error(t, SelectArrayContains(ctx, db, got, &arrayModel{}, "integers", Array[int]{1}))
+	})
+}
+
+func TestSelectByIDs(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p2 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p3 := &personModel{Name: "Averell Dalton", Email: NullString("averell@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2, p3}))
+	require.NoError(t, db.Delete(ctx, p3))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		got, err := SelectByIDs[personModel](ctx, db, []string{p1.GetID(), p2.GetID()})
+		assert.NoError(t, err)
+		assertEqualPersons(t, []*personModel{p1, p2}, got)
+	})
+
+	t.Run("ok deleted", func(t *testing.T) {
+		got, err := SelectByIDs[personModel](ctx, db, []string{p2.GetID(), p3.GetID()})
+		assert.NoError(t, err)
+		assertEqualPersons(t, []*personModel{p2}, got)
+	})
+
+	t.Run("ok missing", func(t *testing.T) {
+		got, err := SelectByIDs[personModel](ctx, db, []string{"3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1"})
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("ok empty", func(t *testing.T) {
+		got, err := SelectByIDs[personModel](ctx, db, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []*personModel{}, got)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		_, err := SelectByIDs[personModel](ctx, db, []string{"not-a-uuid"})
+		assert.Error(t, err)
+	})
+}
+
+func TestDB_SelectScoped(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p2 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2}))
+	require.NoError(t, db.Delete(ctx, p2))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		var got personModel
+		assert.NoError(t, db.SelectScoped(ctx, &got, p1.GetID(), map[string]any{
+			"name": "Lucky Luke", "email": "lucky@example.com",
+		}))
+		assertEqualPerson(t, p1, &got)
+	})
+
+	t.Run("ok empty scope", func(t *testing.T) {
+		var got personModel
+		assert.NoError(t, db.SelectScoped(ctx, &got, p1.GetID(), nil))
+		assertEqualPerson(t, p1, &got)
+	})
+
+	t.Run("fail out of scope", func(t *testing.T) {
+		var got personModel
+		assert.ErrorIs(t, db.SelectScoped(ctx, &got, p1.GetID(), map[string]any{
+			"name": "Lucky Luke", "email": "joe@example.com",
+		}), sql.ErrNoRows)
+	})
+
+	t.Run("fail deleted", func(t *testing.T) {
+		var got personModel
+		assert.ErrorIs(t, db.SelectScoped(ctx, &got, p2.GetID(), map[string]any{
+			"email": "joe@example.com",
+		}), sql.ErrNoRows)
+	})
+
+	t.Run("fail unknown column", func(t *testing.T) {
+		var got personModel
+		assert.Error(t, db.SelectScoped(ctx, &got, p1.GetID(), map[string]any{
+			"tenant_id": "foo",
+		}))
+		assert.Error(t, db.SelectScoped(ctx, &got, p1.GetID(), map[string]any{
+			"1 = 1 OR id": p1.GetID(),
+		}))
+	})
+}
+
+func TestDB_SelectLimit(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Averell Dalton", Email: NullString("averell@example.com")}
+	p2 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+	p3 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p4 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p5 := &personModel{Name: "William Dalton", Email: NullString("william@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2, p3, p4, p5}))
+	require.NoError(t, db.Delete(ctx, p2))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	tests := []struct {
+		name    string
+		where   string
+		orderBy string
+		limit   int
+		offset  int
+		args    []any
+		want    []*personModel
+	}{
+		{"first page", "", "name", 2, 0, nil, []*personModel{p1, p3}},
+		{"second page", "", "name", 2, 2, nil, []*personModel{p4, p5}},
+		{"last page", "", "name", 2, 4, nil, []*personModel{}},
+		{"where", "name LIKE ? OR name = ?", "name DESC", 2, 1, []any{"% Dalton", "Lucky Luke"}, []*personModel{p4, p3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []*personModel{}
+			assert.NoError(t, db.SelectLimit(ctx, &got, &personModel{}, tt.where, tt.orderBy, tt.limit, tt.offset, tt.args...))
+			assertEqualPersons(t, tt.want, got)
+		})
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		var got []*personModel
+		assert.Error(t, db.SelectLimit(ctx, &got, &personModel{}, "", "name", 0, 0))
+		assert.Error(t, db.SelectLimit(ctx, &got, &personModel{}, "", "name", 10, -1))
+		assert.Error(t, db.SelectLimit(ctx, &got, &personModel{}, "missing = ?", "name", 10, 0, 1))
+	})
+}
+
+func TestDB_SelectOrdered(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p2 := &personModel{Name: "Joe Dalton", Email: NullString("joe.dalton@example.com")}
+	p3 := &personModel{Name: "Averell Dalton", Email: NullString("averell@example.com")}
+	p4 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2, p3, p4}))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	tests := []struct {
+		name   string
+		where  string
+		order  Order
+		limit  int
+		offset int
+		args   []any
+		want   []*personModel
+	}{
+		{"asc", "", Asc("name").Asc("email"), 3, 0, nil, []*personModel{p3, p2, p1}},
+		{"desc", "", Desc("name").Desc("email"), 2, 1, nil, []*personModel{p1, p2}},
+		{"where", "name LIKE ?", Desc("email"), 10, 0, []any{"% Dalton"}, []*personModel{p1, p2, p3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []*personModel{}
+			assert.NoError(t, db.SelectOrdered(ctx, &got, &personModel{}, tt.where, tt.order, tt.limit, tt.offset, tt.args...))
+			assertEqualPersons(t, tt.want, got)
+		})
+	}
+
+	t.Run("ok parsed", func(t *testing.T) {
+		order, err := ParseOrder("-name,email")
+		require.NoError(t, err)
+		got := []*personModel{}
+		assert.NoError(t, db.SelectOrdered(ctx, &got, &personModel{}, "", order, 2, 0))
+		assertEqualPersons(t, []*personModel{p4, p2}, got)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var got []*personModel
+		assert.Error(t, db.SelectOrdered(ctx, &got, &personModel{}, "", Asc("name; DROP TABLE person_test"), 10, 0))
+		assert.Error(t, db.SelectOrdered(ctx, &got, &personModel{}, "", Asc("phone"), 10, 0))
+		assert.Error(t, db.SelectOrdered(ctx, &got, &personModel{}, "", Asc("name"), 0, 0))
+	})
+}
+
+func Test_selectForUpdateWhere(t *testing.T) {
+	b, err := queryBuilder(&personModel{})
+	require.NoError(t, err)
+
+	const (
+		selectAllQ = "SELECT id, created_at, updated_at, deleted_at, name, email FROM person_test WHERE "
+		selectQ    = selectAllQ + "(name = ?) AND deleted_at IS NULL"
+	)
+
+	tests := []struct {
+		name      string
+		where     string
+		limit     int
+		opts      []LockOption
+		want      string
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok", "name = ?", 0, nil, selectQ + " FOR UPDATE", assert.NoError},
+		{"ok limit", "name = ?", 10, nil, selectQ + " LIMIT 10 FOR UPDATE", assert.NoError},
+		{"ok skip locked", "name = ?", 10, []LockOption{LockOrderBy("created_at"), SkipLocked()}, selectQ + " ORDER BY created_at LIMIT 10 FOR UPDATE SKIP LOCKED", assert.NoError},
+		{"ok no wait", "name = ?", 0, []LockOption{NoWait(), LockOrderBy("name DESC, created_at")}, selectQ + " ORDER BY name DESC, created_at FOR UPDATE NOWAIT", assert.NoError},
+		{"ok no key update", "name = ?", 0, []LockOption{ForNoKeyUpdate()}, selectQ + " FOR NO KEY UPDATE", assert.NoError},
+		{"ok share", "name = ?", 10, []LockOption{ForShare(), NoWait()}, selectQ + " LIMIT 10 FOR SHARE NOWAIT", assert.NoError},
+		{"ok key share", "name = ?", 0, []LockOption{SkipLocked(), ForKeyShare()}, selectQ + " FOR KEY SHARE SKIP LOCKED", assert.NoError},
+		{"ok or", "name = ? OR email = ?", 0, nil, selectAllQ + "(name = ? OR email = ?) AND deleted_at IS NULL FOR UPDATE", assert.NoError},
+		{"ok empty", "", 1, nil, selectAllQ + "(true) AND deleted_at IS NULL LIMIT 1 FOR UPDATE", assert.NoError},
+		{"fail", "name = ?", 0, []LockOption{NoWait(), SkipLocked()}, "", assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := new(lockOptions)
+			for _, fn := range tt.opts {
+				fn(o)
+			}
+			got, err := selectForUpdateWhere(b, tt.where, tt.limit, o)
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTx_SelectForUpdateWhere(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p2 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+	p3 := &personModel{Name: "William Dalton", Email: NullString("william@example.com")}
+	p4 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2, p3, p4}))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	tx1, err := db.Begin(ctx)
+	require.NoError(t, err)
+	defer tx1.Rollback()
+	tx2, err := db.Begin(ctx)
+	require.NoError(t, err)
+	defer tx2.Rollback()
+
+	var got1, got2, got3 []*personModel
+	assert.NoError(t, tx1.SelectForUpdateWhere(&got1, &personModel{}, "name LIKE ?", 2, LockArgs("% Dalton"), LockOrderBy("name"), SkipLocked()))
+	assertEqualPersons(t, []*personModel{p2, p1}, got1)
+
+	// Locked rows are skipped
+	assert.NoError(t, tx2.SelectForUpdateWhere(&got2, &personModel{}, "name LIKE ?", 2, LockArgs("% Dalton"), LockOrderBy("name"), SkipLocked()))
+	assertEqualPersons(t, []*personModel{p3}, got2)
+
+	// Locked rows fail with NOWAIT
+	assert.Error(t, tx2.SelectForUpdateWhere(&got3, &personModel{}, "name LIKE ?", 0, LockArgs("Joe %"), NoWait()))
+
+	// Shared locks do not conflict
+	tx3, err := db.Begin(ctx)
+	require.NoError(t, err)
+	defer tx3.Rollback()
+	tx4, err := db.Begin(ctx)
+	require.NoError(t, err)
+	defer tx4.Rollback()
+
+	var got4, got5, got6 []*personModel
+	assert.NoError(t, tx3.SelectForUpdateWhere(&got4, &personModel{}, "name = ?", 0, LockArgs("Lucky Luke"), ForShare()))
+	assertEqualPersons(t, []*personModel{p4}, got4)
+	assert.NoError(t, tx4.SelectForUpdateWhere(&got5, &personModel{}, "name = ?", 0, LockArgs("Lucky Luke"), ForKeyShare(), NoWait()))
+	assertEqualPersons(t, []*personModel{p4}, got5)
+	assert.Error(t, tx4.SelectForUpdateWhere(&got6, &personModel{}, "name = ?", 0, LockArgs("Lucky Luke"), ForNoKeyUpdate(), NoWait()))
+
+	// Soft-deleted rows are excluded from every branch of the condition
+	p5 := &personModel{Name: "Averell Dalton", Email: NullString("averell@example.com")}
+	require.NoError(t, db.Insert(ctx, p5))
+	require.NoError(t, db.Delete(ctx, p5))
+	tx5, err := db.Begin(ctx)
+	require.NoError(t, err)
+	defer tx5.Rollback()
+
+	var got7 []*personModel
+	assert.NoError(t, tx5.SelectForUpdateWhere(&got7, &personModel{}, "name = ? OR email = ?", 0, LockArgs("Lucky Luke", "averell@example.com"), ForShare()))
+	assertEqualPersons(t, []*personModel{p4}, got7)
+}
+
+func TestNamedSelect(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p2 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+	p3 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2, p3}))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	type filter struct {
+		Pattern string `db:"pattern"`
+	}
+
+	t.Run("select", func(t *testing.T) {
+		got, err := NamedSelect[personModel](ctx, db, "SELECT * FROM person_test WHERE name LIKE :pattern", filter{"% Dalton"})
+		assert.NoError(t, err)
+		assertEqualPersons(t, []*personModel{p1, p2}, got)
+
+		got, err = NamedSelect[personModel](ctx, db, "SELECT * FROM person_test WHERE name LIKE :pattern", map[string]any{"pattern": "Lucky %"})
+		assert.NoError(t, err)
+		assertEqualPersons(t, []*personModel{p3}, got)
+
+		got, err = NamedSelect[personModel](ctx, db, "SELECT * FROM person_test WHERE name LIKE :pattern", filter{"Averell %"})
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+
+		names, err := NamedSelect[string](ctx, db, "SELECT name FROM person_test WHERE name LIKE :pattern ORDER BY name", filter{"% Dalton"})
+		assert.NoError(t, err)
+		if assert.Len(t, names, 2) {
+			assert.Equal(t, "Jack Dalton", *names[0])
+			assert.Equal(t, "Joe Dalton", *names[1])
+		}
+	})
+
+	t.Run("getOne", func(t *testing.T) {
+		got, err := NamedGetOne[personModel](ctx, db, "SELECT * FROM person_test WHERE email = :email", p3)
+		assert.NoError(t, err)
+		assertEqualPerson(t, p3, got)
+
+		_, err = NamedGetOne[personModel](ctx, db, "SELECT * FROM person_test WHERE name = :pattern", filter{"Averell Dalton"})
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		_, err := NamedSelect[personModel](ctx, db, "SELECT * FROM person_test WHERE name = :missing", filter{"Joe Dalton"})
+		assert.Error(t, err)
+		_, err = NamedGetOne[personModel](ctx, db, "SELECT * FROM person_test WHERE name = :missing", filter{"Joe Dalton"})
+		assert.Error(t, err)
+		_, err = NamedSelect[personModel](ctx, db, "SELECT * FROM missing_table WHERE name = :pattern", filter{"Joe Dalton"})
+		assert.Error(t, err)
+	})
+}
+
+func TestDB_SearchText(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.Insert(ctx, p))
+	n1 := &noteModel{PersonID: p.GetID(), Body: "Shoots faster than his shadow"}
+	n2 := &noteModel{PersonID: p.GetID(), Body: "Chases the Dalton brothers, the Dalton brothers escape"}
+	n3 := &noteModel{PersonID: p.GetID(), Body: "Captures a Dalton"}
+	n4 := &noteModel{PersonID: p.GetID(), Body: "Captures another Dalton"}
+	require.NoError(t, db.InsertBatch(ctx, []Model{n1, n2, n3, n4}))
+	require.NoError(t, db.Delete(ctx, n4))
+
+	ids := func(notes []noteModel) []string {
+		var s []string
+		for _, n := range notes {
+			s = append(s, n.GetID())
+		}
+		return s
+	}
+
+	var notes []noteModel
+	assert.NoError(t, db.SearchText(ctx, &notes, &noteModel{}, "search", "shadow"))
+	assert.Equal(t, []string{n1.GetID()}, ids(notes))
+
+	// Best matches first, without soft-deleted rows
+	notes = nil
+	assert.NoError(t, db.SearchText(ctx, &notes, &noteModel{}, "search", "dalton"))
+	assert.Equal(t, []string{n2.GetID(), n3.GetID()}, ids(notes))
+
+	// The query is plain text
+	notes = nil
+	assert.NoError(t, db.SearchText(ctx, &notes, &noteModel{}, "search", "dalton' & shadow); DROP TABLE note_test; --"))
+	assert.Empty(t, notes)
+
+	assert.Error(t, db.SearchText(ctx, &notes, &noteModel{}, "search; DROP TABLE note_test", "dalton"))
+	assert.Error(t, db.SearchText(ctx, &notes, &noteModel{}, "missing_column", "dalton"))
+}
@@ -2,17 +2,29 @@ package sequel
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-sqlx/sqlx"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	// use pgx/v5 driver
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5/stdlib"
 
+	"go.step.sm/qb"
 	"go.step.sm/sequel/clock"
 )
 
@@ -20,13 +32,34 @@ import (
 // this value, the requests will wait until one connection is free.
 const MaxOpenConnections = 100
 
+// ErrPoolExhausted is the error returned when a connection cannot be acquired
+// from the pool before the timeout set with [WithAcquireTimeout].
+var ErrPoolExhausted = errors.New("timeout acquiring a database connection")
+
+// ErrReadOnly is the error returned by the write methods of a DB created with
+// [WithReadOnly].
+var ErrReadOnly = errors.New("database is read-only")
+
 // DB is the type that holds the database client and adds support for database
 // operations on a Model.
 type DB struct {
-	db            *sqlx.DB
-	clock         clock.Clock
-	doRebindModel bool
-	driverName    string
+	db             *sqlx.DB
+	clock          clock.Clock
+	doRebindModel  bool
+	driverName     string
+	acquireTimeout time.Duration
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	readOnly       bool
+	queryErrors    bool
+	connRetries    int
+	trackQueries   bool
+	txObserver     TxObserver
+	idRetries      int
+	newID          func() string
+	openTxs        atomic.Int64
+	leakDetector   *txLeakDetector
+	types          *typeMap
 }
 
 type options struct {
@@ -34,6 +67,32 @@ type options struct {
 	DriverName         string
 	RebindModel        bool
 	MaxOpenConnections int
+	AcquireTimeout     time.Duration
+	QueryTimeout       time.Duration
+	ReadTimeout        time.Duration
+	WriteTimeout       time.Duration
+	ReadOnly           bool
+	StatementCacheSize int
+	TLSConfig          *tls.Config
+	CredentialProvider CredentialProvider
+	CancelRequest      bool
+	CancelDelay        time.Duration
+	StatementTimeout   time.Duration
+	Logger             *slog.Logger
+	UnsafeScan         bool
+	TxLeakDetection    bool
+	TxLeakStacks       bool
+	TxLeakLogger       *slog.Logger
+	NamingStrategy     func(fieldName string) string
+	Warmup             int
+	QueryErrors        bool
+	ConnRetries        int
+	TrackQueries       bool
+	TxObserver         TxObserver
+	IDRetries          int
+	NewID              func() string
+	LazyConnect        bool
+	TypeMap            *pgtype.Map
 }
 
 func newOptions(driverName string) *options {
@@ -52,6 +111,24 @@ func (o *options) apply(opts []Option) *options {
 	return o
 }
 
+// readTimeout returns the timeout for the read methods, the query timeout if
+// no read timeout is set.
+func (o *options) readTimeout() time.Duration {
+	if o.ReadTimeout > 0 {
+		return o.ReadTimeout
+	}
+	return o.QueryTimeout
+}
+
+// writeTimeout returns the timeout for the write methods, the query timeout if
+// no write timeout is set.
+func (o *options) writeTimeout() time.Duration {
+	if o.WriteTimeout > 0 {
+		return o.WriteTimeout
+	}
+	return o.QueryTimeout
+}
+
 // Option is the type of options that can be used to modify the database. This
 // can be useful for testing purposes.
 type Option func(*options)
@@ -63,6 +140,12 @@ func WithClock(c clock.Clock) Option {
 	}
 }
 
+// WithNowFunc sets a clock to the database that uses the given function to get
+// the current time. It is a shortcut for WithClock(clock.NewFunc(fn)).
+func WithNowFunc(fn func() time.Time) Option {
+	return WithClock(clock.NewFunc(fn))
+}
+
 // WithDriver defines the driver to use, defaults to pgx/v5. This default driver
 // is automatically loaded by this package, any other driver must be loaded by
 // the user.
@@ -88,46 +171,557 @@ func WithMaxOpenConnections(n int) Option {
 	}
 }
 
-// New creates a new DB. It will fail if it cannot ping it.
+// WithAcquireTimeout sets the maximum time a query waits to check out a
+// connection from the pool. If a connection cannot be acquired in time the
+// query fails with [ErrPoolExhausted]. By default, queries wait until a
+// connection is free or the context is done.
+func WithAcquireTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.AcquireTimeout = d
+	}
+}
+
+// WithQueryTimeout sets a timeout for every query run by the DB methods. Each
+// method derives a context with the given timeout from the one passed, if the
+// passed context has a shorter deadline, the shorter one is used. The timeout
+// also applies to the rows returned by the Query methods until they are
+// closed, and to InsertBatch as a whole, but not to transactions started with
+// Begin. Use [WithReadTimeout] and [WithWriteTimeout] to set different
+// timeouts for reads and writes.
+func WithQueryTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.QueryTimeout = d
+	}
+}
+
+// WithReadTimeout sets a timeout for the read methods, Query, QueryRow, Get,
+// GetAll, GetJSON, Call, and the methods based on them like Select, overriding
+// the one set with [WithQueryTimeout]. It works like the query timeout but
+// allows reads to fail faster than writes.
+func WithReadTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.ReadTimeout = d
+	}
+}
+
+// WithWriteTimeout sets a timeout for the write methods, Exec, Insert,
+// InsertBatch, Update, Delete, HardDelete, Upsert, and the methods based on
+// them, overriding the one set with [WithQueryTimeout]. It also applies to
+// the transactions started with RunInTx as a whole, from the beginning of the
+// transaction to the commit.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.WriteTimeout = d
+	}
+}
+
+// WithReadOnly marks the database as read-only, useful when connecting to a
+// replica. The methods Insert, InsertBatch, Update, Delete, HardDelete, Exec,
+// RebindExec, NamedExec, and Begin will fail with [ErrReadOnly] without
+// hitting the database. Use BeginReadOnly to start a read-only transaction.
+//
+// Queries run with the Query methods are not inspected, so statements like
+// `INSERT ... RETURNING` will still be sent to the database.
+func WithReadOnly() Option {
+	return func(o *options) {
+		o.ReadOnly = true
+	}
+}
+
+// WithStatementCache sets the capacity of the per-connection cache of prepared
+// statements. The pgx driver already prepares the statements on first use and
+// caches them, keyed by the final SQL sent to the database, keeping by default
+// at most 512 statements per connection and evicting the least recently used
+// ones. A size of 0 keeps the default capacity.
+//
+// This option is only supported by New with a pgx driver, which implements the
+// cache.
+func WithStatementCache(size int) Option {
+	return func(o *options) {
+		o.StatementCacheSize = size
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used to connect to the database,
+// for example, to authenticate with a client certificate. The configuration
+// replaces the one derived from the sslmode, sslcert, sslkey, and sslrootcert
+// parameters of the data source name, and the connection does not fall back
+// to plaintext.
+//
+// This option is only supported by New with a pgx driver.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.TLSConfig = cfg
+	}
+}
+
+// CredentialProvider is the type of the functions that return the user and
+// password used to open a new connection to the database.
+type CredentialProvider func(ctx context.Context) (user, password string, err error)
+
+// WithCredentialProvider sets a function that is called every time a new
+// connection is opened to get the user and password, replacing the ones in the
+// data source name. It allows the use of short-lived credentials that are
+// rotated without restarting the application. Existing connections are not
+// affected by the rotation, use DB().SetConnMaxLifetime to recycle them.
+//
+// This option is only supported by New with a pgx driver.
+func WithCredentialProvider(fn CredentialProvider) Option {
+	return func(o *options) {
+		o.CredentialProvider = fn
+	}
+}
+
+// WithCancelRequest makes the queries whose context is done send a cancel
+// request to the server, the same as pg_cancel_backend, so they stop running on
+// the server too. By default, the connection is closed when the context is
+// done, and the server may keep running the query until it notices it. If the
+// query does not finish after the given delay, the connection is closed.
+//
+// This option is only supported by New with a pgx driver.
+func WithCancelRequest(deadlineDelay time.Duration) Option {
+	return func(o *options) {
+		o.CancelRequest = true
+		o.CancelDelay = deadlineDelay
+	}
+}
+
+// WithStatementTimeout sets the statement_timeout parameter on each new
+// connection, so the server aborts any statement that takes longer than the
+// given duration, regardless of the context passed to the query. Unlike the
+// query timeouts, it is enforced by the server, and it is rounded down to
+// milliseconds. By default, there is no statement timeout.
+//
+// This option is only supported by New with a pgx driver.
+func WithStatementTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.StatementTimeout = d
+	}
+}
+
+// WithSlog logs every query with the given logger, the successful ones at debug
+// level and the failed ones at error level, including their duration and their
+// SQL, truncated to 1024 bytes. The query arguments are never logged, as they
+// might contain sensitive data.
+//
+// This option is only supported by New with a pgx driver, which traces the
+// queries.
+func WithSlog(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.Logger = logger
+	}
+}
+
+// WithUnsafeScan ignores the columns without a matching field in the
+// destination when scanning rows into structs, instead of failing with a
+// "missing destination name" error. It allows services to keep reading tables
+// with new columns, for example, during a rolling deployment of a schema
+// change. As a side effect, misspelled db tags are not reported either.
+func WithUnsafeScan() Option {
+	return func(o *options) {
+		o.UnsafeScan = true
+	}
+}
+
+// WithNamingStrategy sets the function used to get the column name of the
+// struct fields without a db tag when scanning rows or binding named
+// parameters, e.g. a function that converts CreatedAt to created_at. By
+// default, the column name is the field name in lower case.
+//
+// The queries generated from the models, like the ones returned by Queries,
+// only include the fields with a db tag, and the table names are not affected
+// by the naming strategy, see [NewBuilder].
+func WithNamingStrategy(fn func(fieldName string) string) Option {
+	return func(o *options) {
+		o.NamingStrategy = fn
+	}
+}
+
+// WithWarmup opens and pings n connections when the database is created, so
+// the pool is already filled when the first queries arrive. If n is greater
+// than 2, the default number of idle connections kept by the pool, the maximum
+// number of idle connections is raised to n. The number of connections is
+// limited by the maximum number of open connections.
+func WithWarmup(n int) Option {
+	return func(o *options) {
+		o.Warmup = n
+	}
+}
+
+// WithQueryErrors enables the wrapping of the errors returned by the model
+// operations, like Select, Insert, InsertBatch, Update, Delete, HardDelete, or
+// Upsert, and their Tx versions, in a [*QueryError] with the operation, table
+// and query that failed. Errors from methods that run arbitrary queries, like
+// Query, Exec, or Get, are not wrapped. It is disabled by default to avoid the
+// extra allocations.
+func WithQueryErrors() Option {
+	return func(o *options) {
+		o.QueryErrors = true
+	}
+}
+
+// WithRetryOnConnError retries the read methods, Query, RebindQuery, Get,
+// GetAll, GetJSON, and the methods based on them like Select, up to the given
+// number of times if they fail with a connection error, see [IsConnError]. Each
+// attempt gets a new connection from the pool, after a delay that starts at
+// 50ms and doubles on each retry, up to 5s. It is useful during database
+// failovers or pooler restarts.
+//
+// The write methods are never retried to avoid duplicates. The queries run
+// with the read methods must be idempotent, for example, a query like `INSERT
+// ... RETURNING` must not be run with Get.
+func WithRetryOnConnError(attempts int) Option {
+	return func(o *options) {
+		o.ConnRetries = attempts
+	}
+}
+
+// WithLastQueryTracking enables the tracking of the last query started in each
+// transaction, available with Tx.LastQuery. If fn panics in RunInTx, the panic
+// is re-raised with a [*TxPanic] that includes the last query, making it easier
+// to diagnose the query that was running. It is a debugging aid, disabled by
+// default to avoid the overhead on each query.
+func WithLastQueryTracking() Option {
+	return func(o *options) {
+		o.TrackQueries = true
+	}
+}
+
+// WithTxLeakDetection logs a warning when a transaction is garbage collected
+// without being committed or rolled back, as its connection is not returned to
+// the pool until the database closes it. If stacks is true, the warning also
+// includes the stack trace of the code that began the transaction, which is
+// expensive to capture, so it should only be enabled while debugging.
+//
+// The warning is logged with the given logger, or with the default slog
+// logger if it is nil. As it depends on the garbage collector, a leak might be
+// reported long after it happened, or never, so it is meant for development
+// and testing, see also DB.OpenTransactions.
+func WithTxLeakDetection(logger *slog.Logger, stacks bool) Option {
+	return func(o *options) {
+		o.TxLeakDetection = true
+		o.TxLeakStacks = stacks
+		o.TxLeakLogger = logger
+	}
+}
+
+// WithTxObserver sets a function that is called when a transaction ends, with
+// the time it has been open, whether it has been committed, and the error
+// returned by the commit or rollback. It allows, for example, alerting on
+// long-running transactions or on unexpected rollbacks. The function is called
+// once per transaction, on the first Commit or Rollback, and it must not block.
+func WithTxObserver(fn TxObserver) Option {
+	return func(o *options) {
+		o.TxObserver = fn
+	}
+}
+
+// WithIDCollisionRetry makes Insert retry the insert of a model implementing
+// ModelWithExecInsert, with an id generated by the client, if it fails because
+// the id already exists. Before each retry, up to n times, the id of the model
+// is replaced with the one returned by newID. Unique violations on other
+// columns are returned as usual.
+//
+// The violation is detected by the name of the constraint, that must be the
+// default one for a primary key, the table name followed by _pkey. Inserts in a
+// transaction are not retried, as the failed insert aborts the transaction.
+func WithIDCollisionRetry(n int, newID func() string) Option {
+	return func(o *options) {
+		o.IDRetries = n
+		o.NewID = newID
+	}
+}
+
+// WithLazyConnect makes New and NewDB return the DB without connecting to the
+// database. The connections are opened on first use, so a service can start
+// before the database is ready, and the queries fail until it is reachable.
+// Use [WithRetryOnConnError] to retry the reads with backoff on connection
+// errors until the database is ready. The connections set with [WithWarmup]
+// are not opened.
+func WithLazyConnect() Option {
+	return func(o *options) {
+		o.LazyConnect = true
+	}
+}
+
+// WithTypeMap sets the type map where DB.LoadType, DB.RegisterDefaultPgType and
+// LoadEnum register the types of the database, instead of the default type map
+// shared by all the DBs, see [RegisterType]. The map can also be populated
+// before creating the DB, but it must not be modified directly after that.
+//
+// The [Array], [Composite] and [Enum] values do not know the DB running the
+// query, so they are scanned and encoded with the first type map where their
+// Go type is mapped to a PostgreSQL type, the default one or, if it is not
+// mapped there, the map of an open DB. A Go type must therefore be mapped to
+// the same type in all the maps; DB.RegisterDefaultPgType and LoadEnum fail if
+// it is not, but the mappings added to the map before creating the DB are not
+// checked. Each map is locked independently, so the types of a DB do not
+// contend with other DBs.
+func WithTypeMap(m *pgtype.Map) Option {
+	return func(o *options) {
+		o.TypeMap = m
+	}
+}
+
+// New creates a new DB. It will fail if it cannot ping it, unless
+// [WithLazyConnect] is used.
 func New(dataSourceName string, opts ...Option) (*DB, error) {
 	options := newOptions("pgx/v5").apply(opts)
 
 	// Connect opens the database and verifies with a ping
-	db, err := sqlx.Connect(options.DriverName, dataSourceName)
+	var db *sqlx.DB
+	var err error
+	switch {
+	case options.connConfig():
+		db, err = connectWithConfig(dataSourceName, options)
+	case options.LazyConnect:
+		db, err = sqlx.Open(options.DriverName, dataSourceName)
+	default:
+		db, err = sqlx.Connect(options.DriverName, dataSourceName)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to the database: %w", err)
 	}
 	db.SetMaxOpenConns(options.MaxOpenConnections)
+	if options.NamingStrategy != nil {
+		db.MapperFunc(options.NamingStrategy)
+	}
+	if options.UnsafeScan {
+		db = db.Unsafe()
+	}
+	if err := warmup(db, options); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error warming up the database: %w", err)
+	}
 
 	return &DB{
-		db:            db,
-		clock:         options.Clock,
-		doRebindModel: options.RebindModel,
-		driverName:    options.DriverName,
+		db:             db,
+		clock:          options.Clock,
+		doRebindModel:  options.RebindModel,
+		driverName:     options.DriverName,
+		acquireTimeout: options.AcquireTimeout,
+		readTimeout:    options.readTimeout(),
+		writeTimeout:   options.writeTimeout(),
+		readOnly:       options.ReadOnly,
+		queryErrors:    options.QueryErrors,
+		connRetries:    options.ConnRetries,
+		trackQueries:   options.TrackQueries,
+		txObserver:     options.TxObserver,
+		idRetries:      options.IDRetries,
+		newID:          options.NewID,
+		leakDetector:   newTxLeakDetector(options.TxLeakDetection, options.TxLeakStacks, options.TxLeakLogger),
+		types:          newDBTypes(options.TypeMap),
 	}, nil
 }
 
+// connConfig returns true if the options require a pgx connection config.
+func (o *options) connConfig() bool {
+	return o.StatementCacheSize > 0 || o.TLSConfig != nil || o.CredentialProvider != nil ||
+		o.CancelRequest || o.StatementTimeout > 0 || o.Logger != nil
+}
+
+// connectWithConfig opens a pgx database using a connection config with the
+// statement cache, TLS, credential, cancel request, statement timeout and
+// logger options, and verifies the connection with a ping if the lazy connect
+// option is not set.
+func connectWithConfig(dataSourceName string, o *options) (*sqlx.DB, error) {
+	if o.DriverName != "pgx" && o.DriverName != "pgx/v5" {
+		switch {
+		case o.StatementCacheSize > 0:
+			return nil, fmt.Errorf("statement cache is not supported by driver %q", o.DriverName)
+		case o.TLSConfig != nil:
+			return nil, fmt.Errorf("tls config is not supported by driver %q", o.DriverName)
+		case o.CancelRequest:
+			return nil, fmt.Errorf("cancel request is not supported by driver %q", o.DriverName)
+		case o.StatementTimeout > 0:
+			return nil, fmt.Errorf("statement timeout is not supported by driver %q", o.DriverName)
+		case o.Logger != nil:
+			return nil, fmt.Errorf("slog logger is not supported by driver %q", o.DriverName)
+		default:
+			return nil, fmt.Errorf("credential provider is not supported by driver %q", o.DriverName)
+		}
+	}
+	config, err := pgx.ParseConfig(dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	if o.StatementCacheSize > 0 {
+		config.StatementCacheCapacity = o.StatementCacheSize
+	}
+	if o.TLSConfig != nil {
+		applyTLSConfig(config, o.TLSConfig)
+	}
+	if o.StatementTimeout > 0 {
+		config.RuntimeParams["statement_timeout"] = strconv.FormatInt(o.StatementTimeout.Milliseconds(), 10)
+	}
+	if o.Logger != nil {
+		config.Tracer = &slogTracer{logger: o.Logger}
+	}
+	if o.CancelRequest {
+		delay := o.CancelDelay
+		config.BuildContextWatcherHandler = func(conn *pgconn.PgConn) ctxwatch.Handler {
+			return &pgconn.CancelRequestContextWatcherHandler{Conn: conn, DeadlineDelay: delay}
+		}
+	}
+
+	var openOpts []stdlib.OptionOpenDB
+	if fn := o.CredentialProvider; fn != nil {
+		openOpts = append(openOpts, stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+			user, password, err := fn(ctx)
+			if err != nil {
+				return fmt.Errorf("error getting database credentials: %w", err)
+			}
+			cc.User = user
+			cc.Password = password
+			return nil
+		}))
+	}
+
+	db := sqlx.NewDb(stdlib.OpenDB(*config, openOpts...), o.DriverName)
+	if o.LazyConnect {
+		return db, nil
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// applyTLSConfig sets the given TLS configuration on the connection config and
+// on its fallbacks, that include the other hosts of a multi-host data source
+// name. The server name of each configuration is its host, unless it is set or
+// the verification is skipped. The fallbacks to the same host and port, like
+// the plaintext ones added by sslmode=prefer, are removed.
+func applyTLSConfig(config *pgx.ConnConfig, tlsConfig *tls.Config) {
+	type hostPort struct {
+		host string
+		port uint16
+	}
+	seen := map[hostPort]bool{{config.Host, config.Port}: true}
+	config.TLSConfig = hostTLSConfig(tlsConfig, config.Host)
+	fallbacks := make([]*pgconn.FallbackConfig, 0, len(config.Fallbacks))
+	for _, fb := range config.Fallbacks {
+		if hp := (hostPort{fb.Host, fb.Port}); !seen[hp] {
+			seen[hp] = true
+			fb.TLSConfig = hostTLSConfig(tlsConfig, fb.Host)
+			fallbacks = append(fallbacks, fb)
+		}
+	}
+	config.Fallbacks = fallbacks
+}
+
+// hostTLSConfig returns a copy of the given TLS configuration with the server
+// name set to host, unless it is already set or the verification is skipped.
+func hostTLSConfig(tlsConfig *tls.Config, host string) *tls.Config {
+	c := tlsConfig.Clone()
+	if c.ServerName == "" && !c.InsecureSkipVerify {
+		c.ServerName = host
+	}
+	return c
+}
+
 // NewDB creates a new DB wrapping the opened database handle with the given
-// driverName. It will fail if it cannot ping it.
+// driverName. It will fail if it cannot ping it, unless [WithLazyConnect] is
+// used.
 func NewDB(db *sql.DB, driverName string, opts ...Option) (*DB, error) {
 	options := newOptions(driverName).apply(opts)
+	if options.StatementCacheSize > 0 {
+		return nil, errors.New("statement cache is not supported on an opened database")
+	}
+	if options.TLSConfig != nil {
+		return nil, errors.New("tls config is not supported on an opened database")
+	}
+	if options.CredentialProvider != nil {
+		return nil, errors.New("credential provider is not supported on an opened database")
+	}
+	if options.CancelRequest {
+		return nil, errors.New("cancel request is not supported on an opened database")
+	}
+	if options.StatementTimeout > 0 {
+		return nil, errors.New("statement timeout is not supported on an opened database")
+	}
+	if options.Logger != nil {
+		return nil, errors.New("slog logger is not supported on an opened database")
+	}
 
 	// Wrap an opened *sql.DB and verify the connection with a ping
 	dbx := sqlx.NewDb(db, options.DriverName)
-	if err := dbx.Ping(); err != nil {
-		dbx.Close()
-		return nil, fmt.Errorf("error connecting to the database: %w", err)
+	if !options.LazyConnect {
+		if err := dbx.Ping(); err != nil {
+			dbx.Close()
+			return nil, fmt.Errorf("error connecting to the database: %w", err)
+		}
 	}
 	dbx.SetMaxOpenConns(options.MaxOpenConnections)
+	if options.NamingStrategy != nil {
+		dbx.MapperFunc(options.NamingStrategy)
+	}
+	if options.UnsafeScan {
+		dbx = dbx.Unsafe()
+	}
+	if err := warmup(dbx, options); err != nil {
+		dbx.Close()
+		return nil, fmt.Errorf("error warming up the database: %w", err)
+	}
 
 	return &DB{
-		db:            dbx,
-		clock:         options.Clock,
-		doRebindModel: options.RebindModel,
-		driverName:    options.DriverName,
+		db:             dbx,
+		clock:          options.Clock,
+		doRebindModel:  options.RebindModel,
+		driverName:     options.DriverName,
+		acquireTimeout: options.AcquireTimeout,
+		readTimeout:    options.readTimeout(),
+		writeTimeout:   options.writeTimeout(),
+		readOnly:       options.ReadOnly,
+		queryErrors:    options.QueryErrors,
+		connRetries:    options.ConnRetries,
+		trackQueries:   options.TrackQueries,
+		txObserver:     options.TxObserver,
+		idRetries:      options.IDRetries,
+		newID:          options.NewID,
+		leakDetector:   newTxLeakDetector(options.TxLeakDetection, options.TxLeakStacks, options.TxLeakLogger),
+		types:          newDBTypes(options.TypeMap),
 	}, nil
 }
 
+// warmup opens and pings the number of connections set in the options and
+// returns them to the pool.
+func warmup(db *sqlx.DB, o *options) error {
+	n := o.Warmup
+	if o.LazyConnect {
+		return nil
+	}
+	if o.MaxOpenConnections > 0 && n > o.MaxOpenConnections {
+		n = o.MaxOpenConnections
+	}
+	if n <= 0 {
+		return nil
+	}
+	if n > 2 {
+		db.SetMaxIdleConns(n)
+	}
+
+	ctx := context.Background()
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		c, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, c)
+		if err := c.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type dbKey struct{}
 
 // NewContext returns a new context with the given DB.
@@ -151,16 +745,59 @@ func IsErrNotFound(err error) bool {
 	return errors.Is(err, sql.ErrNoRows)
 }
 
+// sqlStateError is the interface implemented by the errors with an SQLSTATE
+// code, like *pgconn.PgError, or the errors of other drivers like lib/pq.
+type sqlStateError interface {
+	SQLState() string
+}
+
+// sqlState returns the SQLSTATE code of the given error, or an empty string if
+// the error does not have one.
+func sqlState(err error) string {
+	var e sqlStateError
+	if errors.As(err, &e) {
+		return e.SQLState()
+	}
+	return ""
+}
+
 // IsUniqueViolation returns true if the given error is equal to the postgres
 // unique violation error (23505).
 func IsUniqueViolation(err error) bool {
+	return sqlState(err) == "23505"
+}
+
+// IsForeignKeyViolation returns true if the given error is equal to the
+// postgres foreign key violation error (23503).
+func IsForeignKeyViolation(err error) bool {
+	return sqlState(err) == "23503"
+}
+
+// isPrimaryKeyViolation returns true if the given error is a unique violation
+// of a constraint with the default name of a primary key.
+func isPrimaryKeyViolation(err error) bool {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
-		return pgErr.Code == "23505"
+		return pgErr.Code == "23505" && pgErr.TableName != "" && pgErr.ConstraintName == pgErr.TableName+"_pkey"
 	}
 	return false
 }
 
+// IsDeadlock returns true if the given error is equal to the postgres deadlock
+// detected error (40P01).
+func IsDeadlock(err error) bool {
+	return sqlState(err) == "40P01"
+}
+
+// IsSerializationFailure returns true if the given error is equal to the
+// postgres serialization failure error (40001), returned by serializable or
+// repeatable read transactions that conflict with concurrent ones. Unlike
+// IsDeadlock, it does not report deadlocks, so both contention patterns can be
+// told apart.
+func IsSerializationFailure(err error) bool {
+	return sqlState(err) == "40001"
+}
+
 // RowsAffected checks that the numbers of rows affected matches the given one,
 // if not it will return an error.
 func RowsAffected(res sql.Result, n int64) error {
@@ -177,12 +814,31 @@ func RowsAffected(res sql.Result, n int64) error {
 	return fmt.Errorf("unexpected number of rows: got %d, want %d", got, n)
 }
 
+// AffectedRows returns the number of rows affected by an update, insert, or
+// delete. Unlike RowsAffected, it does not check the number against an
+// expected value.
+func AffectedRows(res sql.Result) (int64, error) {
+	return res.RowsAffected()
+}
+
 // Close closes the database and prevents new queries from starting. Close then
 // waits for all queries that have started processing on the server to finish.
 func (d *DB) Close() error {
+	if d.types != nil {
+		removeDBTypes(d.types)
+	}
 	return d.db.Close()
 }
 
+// typeMap returns the type map of the DB, the default one if WithTypeMap is
+// not used.
+func (d *DB) typeMap() *typeMap {
+	if d.types != nil {
+		return d.types
+	}
+	return defaultTypes
+}
+
 // Driver returns the name of the driver used.
 func (d *DB) Driver() string {
 	return d.driverName
@@ -193,6 +849,42 @@ func (d *DB) DB() *sql.DB {
 	return d.db.DB
 }
 
+// Underlying returns the underlying *sqlx.DB, for interoperability with
+// libraries that require it. Queries run with it do not use the options of the
+// DB, like timeouts, query tags, or the read-only mode.
+func (d *DB) Underlying() *sqlx.DB {
+	return d.db
+}
+
+// PoolWaitStats returns the total number of times a query waited for a free
+// connection in the pool and the total time spent waiting. A rising wait time
+// is an early signal of pool saturation, see [WithMaxOpenConnections].
+func (d *DB) PoolWaitStats() (count int64, total time.Duration) {
+	stats := d.db.Stats()
+	return stats.WaitCount, stats.WaitDuration
+}
+
+// RawConn checks out a connection from the pool and calls fn with the
+// underlying *pgx.Conn, giving access to pgx features like COPY, LISTEN, or
+// custom type registration. The connection is returned to the pool once fn
+// returns, fn must not retain it. RawConn fails if the database does not use
+// a pgx driver.
+func (d *DB) RawConn(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	conn, err := d.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unsupported driver connection %T", driverConn)
+		}
+		return fn(c.Conn())
+	})
+}
+
 // Rebind transforms a query from `?` to the DB driver's bind type.
 func (d *DB) Rebind(query string) string {
 	return d.db.Rebind(query)
@@ -205,10 +897,130 @@ func (d *DB) rebindModel(query string) string {
 	return query
 }
 
+// executor is the interface implemented by *sqlx.DB and *sqlx.Conn.
+type executor interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+}
+
+func noRelease() {}
+
+// conn checks out a single connection from the pool. If an acquire timeout is
+// set, it fails with ErrPoolExhausted if it cannot get one in time.
+func (d *DB) conn(ctx context.Context) (*sqlx.Conn, error) {
+	if d.acquireTimeout <= 0 {
+		return d.db.Connx(ctx)
+	}
+
+	actx, cancel := context.WithTimeout(ctx, d.acquireTimeout)
+	defer cancel()
+
+	conn, err := d.db.Connx(actx)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return nil, ErrPoolExhausted
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// expiredRow returns a *sql.Row that fails with the error of the context if it
+// is done, or with context.DeadlineExceeded otherwise.
+func expiredRow(ctx context.Context, ex executor) *sql.Row {
+	ctx, cancel := context.WithDeadline(ctx, time.Time{})
+	defer cancel()
+	return ex.QueryRowContext(ctx, "SELECT 1")
+}
+
+// acquire returns the context and the executor used to run a query. If the
+// timeout is greater than 0, the returned context is bounded by it. If an
+// acquire timeout is set, it checks out a connection from the pool and fails
+// with ErrPoolExhausted if it cannot get one in time.
+//
+// The release function must always be called once the query has been started.
+// It returns the connection to the pool and cancels the context. As it blocks
+// until any rows or transaction using the connection are closed, queries
+// returning them must release the connection with releaseLater.
+//
+// If the context contains a transaction, see NewTxContext, the query runs in
+// that transaction instead, and neither the timeout nor the acquire timeout
+// apply, the transaction is bounded by its own context.
+func (d *DB) acquire(ctx context.Context, timeout time.Duration) (context.Context, executor, func(), error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return ctx, tagged(ctx, &txExecutor{tx: tx}), noRelease, nil
+	}
+	return d.acquireConn(ctx, timeout)
+}
+
+// acquireConn is like acquire, but it always uses a connection from the pool,
+// even if the context contains a transaction.
+func (d *DB) acquireConn(ctx context.Context, timeout time.Duration) (context.Context, executor, func(), error) {
+	if d.acquireTimeout <= 0 && timeout <= 0 {
+		return ctx, tagged(ctx, d.db), noRelease, nil
+	}
+
+	cancel := noRelease
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	conn, err := d.conn(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+
+	return ctx, tagged(ctx, conn), func() {
+		_ = conn.Close()
+		cancel()
+	}, nil
+}
+
+// releaseLater calls the release function returned by acquire in the
+// background, so the connection is returned to the pool once the rows or the
+// transaction using it are closed.
+func releaseLater(release func()) {
+	go release()
+}
+
 // Query executes a query that returns rows, typically a SELECT. The args are
 // for any placeholder parameters in the query.
-func (d *DB) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	return d.db.QueryContext(ctx, query, args...)
+func (d *DB) Query(ctx context.Context, query string, args ...any) (rows *sql.Rows, err error) {
+	err = d.retry(ctx, func() error {
+		rows, err = d.query(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (d *DB) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, ex, release, err := d.acquire(ctx, d.readTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseLater(release)
+	return ex.QueryContext(ctx, query, args...)
+}
+
+// queryx is like Query, but it returns *sqlx.Rows that scan like the DB, for
+// example, ignoring the unknown columns if WithUnsafeScan is used.
+func (d *DB) queryx(ctx context.Context, query string, args ...any) (rows *sqlx.Rows, err error) {
+	err = d.retry(ctx, func() error {
+		ctx, ex, release, err := d.acquire(ctx, d.readTimeout)
+		if err != nil {
+			return err
+		}
+		defer releaseLater(release)
+		rows, err = ex.QueryxContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
 }
 
 // QueryRow executes a query that is expected to return at most one row.
@@ -218,21 +1030,76 @@ func (d *DB) Query(ctx context.Context, query string, args ...any) (*sql.Rows, e
 // If the query selects no rows, the *Row's Scan will return ErrNoRows.
 // Otherwise, the *Row's Scan scans the first selected row and discards the
 // rest.
+//
+// As a *Row cannot hold custom errors, if a connection cannot be acquired, the
+// *Row's Scan will return the error of the context if it is done, for example,
+// context.Canceled if it was canceled, or context.DeadlineExceeded otherwise,
+// including when the pool is exhausted and Exec and other methods would return
+// ErrPoolExhausted.
 func (d *DB) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
-	return d.db.QueryRowContext(ctx, query, args...)
+	actx, ex, release, err := d.acquire(ctx, d.readTimeout)
+	if err != nil {
+		return expiredRow(ctx, d.db)
+	}
+	defer releaseLater(release)
+	return ex.QueryRowContext(actx, query, args...)
 }
 
 // Exec executes a query without returning any rows. The args are for any
 // placeholder parameters in the query.
 func (d *DB) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return d.db.ExecContext(ctx, query, args...)
+	if d.readOnly {
+		return nil, ErrReadOnly
+	}
+	ctx, ex, release, err := d.acquire(ctx, d.writeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ex.ExecContext(ctx, query, args...)
+}
+
+// ExecExpect executes a query without returning any rows, like Exec, and
+// checks that it affected exactly want rows, see RowsAffected. It returns
+// sql.ErrNoRows if no rows were affected, or an error with the number of rows
+// if it differs from want. The statement is not rolled back in that case, use
+// a transaction to discard its changes.
+func (d *DB) ExecExpect(ctx context.Context, query string, want int64, args ...any) error {
+	r, err := d.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return RowsAffected(r, want)
+}
+
+// ExecReturning executes a data-modifying statement with a RETURNING clause,
+// like an INSERT, UPDATE, or DELETE, or a writable CTE combining them, and
+// populates dest, a pointer to a slice, with all the returned rows. Unlike
+// GetAll, it fails with ErrReadOnly on a read-only DB, it uses the write
+// timeout, and it is never retried on connection errors.
+func (d *DB) ExecReturning(ctx context.Context, dest any, query string, args ...any) error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	ctx, ex, release, err := d.acquire(ctx, d.writeTimeout)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	rows, err := ex.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return sqlx.StructScan(rows, dest)
 }
 
 // Query executes a query that returns rows, typically a SELECT. The query is
 // rebound from `?` to the DB driver's bind type. The args are for any
 // placeholder parameters in the query.
 func (d *DB) RebindQuery(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	return d.db.QueryContext(ctx, d.db.Rebind(query), args...)
+	return d.Query(ctx, d.db.Rebind(query), args...)
 }
 
 // QueryRow executes a query that is expected to return at most one row. The
@@ -244,41 +1111,84 @@ func (d *DB) RebindQuery(ctx context.Context, query string, args ...any) (*sql.R
 // Otherwise, the *Row's Scan scans the first selected row and discards the
 // rest.
 func (d *DB) RebindQueryRow(ctx context.Context, query string, args ...any) *sql.Row {
-	return d.db.QueryRowContext(ctx, d.db.Rebind(query), args...)
+	return d.QueryRow(ctx, d.db.Rebind(query), args...)
 }
 
 // Exec executes a query without returning any rows. The query is rebound from
 // `?` to the DB driver's bind type. The args are for any placeholder parameters
 // in the query.
 func (d *DB) RebindExec(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return d.db.ExecContext(ctx, d.db.Rebind(query), args...)
+	return d.Exec(ctx, d.db.Rebind(query), args...)
 }
 
 // NamedQuery executes a query that returns rows. Any named placeholder
 // parameters are replaced with fields from arg.
 func (d *DB) NamedQuery(ctx context.Context, query string, arg any) (*sqlx.Rows, error) {
-	return d.db.NamedQueryContext(ctx, query, arg)
+	query, args, err := d.db.BindNamed(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	ctx, ex, release, err := d.acquire(ctx, d.readTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseLater(release)
+	return ex.QueryxContext(ctx, query, args...)
 }
 
 // NamedExec using executes a query without returning any rows. Any named
 // placeholder parameters are replaced with fields from arg.
 func (d *DB) NamedExec(ctx context.Context, query string, arg any) (sql.Result, error) {
-	return d.db.NamedExecContext(ctx, query, arg)
+	query, args, err := d.db.BindNamed(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return d.Exec(ctx, query, args...)
+}
+
+// Get populates the given destination with the result of the given select
+// query. The destination is usually a model, but it can be a pointer to any
+// struct with db tags, like a projection of a subset of the columns, or to a
+// scannable value if the query returns a single column, like an [Array] with
+// the result of `SELECT array_agg(name) FROM ...`.
+func (d *DB) Get(ctx context.Context, dest any, query string, args ...any) error {
+	return d.retry(ctx, func() error {
+		return d.get(ctx, d.readTimeout, dest, query, args...)
+	})
+}
+
+func (d *DB) get(ctx context.Context, timeout time.Duration, dest any, query string, args ...any) error {
+	ctx, ex, release, err := d.acquire(ctx, timeout)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return ex.GetContext(ctx, dest, query, args...)
 }
 
-// Get populates the given model for the result of the given select query.
-func (d *DB) Get(ctx context.Context, dest Model, query string, args ...any) error {
-	return d.db.GetContext(ctx, dest, query, args...)
+// GetStruct populates the given destination, a pointer to any struct with db
+// tags, with the result of the given select query. Unlike Get, it fails if the
+// destination is not a pointer to a struct, or if the struct implements
+// sql.Scanner, as the columns would not be mapped to its fields.
+func (d *DB) GetStruct(ctx context.Context, dest any, query string, args ...any) error {
+	if _, ok := dest.(sql.Scanner); ok {
+		return fmt.Errorf("destination %T is a scanner, not a struct with db tags", dest)
+	}
+	if v := reflect.ValueOf(dest); v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("destination %T is not a pointer to a struct", dest)
+	}
+	return d.Get(ctx, dest, query, args...)
 }
 
 // GetAll populates the given destination with all the results of the given
 // select query. The method will fail if the destination is not a pointer to a
 // slice.
 func (d *DB) GetAll(ctx context.Context, dest any, query string, args ...any) error {
-	rows, err := d.db.QueryContext(ctx, query, args...)
+	rows, err := d.queryx(ctx, query, args...)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 	if err := rows.Err(); err != nil {
 		return err
 	}
@@ -286,102 +1196,415 @@ func (d *DB) GetAll(ctx context.Context, dest any, query string, args ...any) er
 }
 
 // Select populates the given model with the result of a select by id query.
-func (d *DB) Select(ctx context.Context, dest Model, id string) error {
-	return d.db.GetContext(ctx, dest, d.rebindModel(dest.Select()), id)
+func (d *DB) Select(ctx context.Context, dest Model, id string) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "select", dest, "", dest.Select())
+	return d.Get(ctx, dest, d.rebindModel(dest.Select()), id)
+}
+
+// Reload refreshes the given model with the current values in the database,
+// using the model's select by id query. All the fields in the model are
+// overwritten, so fields not selected by the query are reset to their zero
+// value. If the row no longer exists, Reload returns sql.ErrNoRows and the model
+// is not modified.
+func (d *DB) Reload(ctx context.Context, m Model) error {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("cannot reload %T: model must be a non-nil pointer", m)
+	}
+
+	fresh := reflect.New(v.Elem().Type())
+	dest, ok := fresh.Interface().(Model)
+	if !ok {
+		return fmt.Errorf("cannot reload %T: %T does not implement Model", m, fresh.Interface())
+	}
+	if err := d.Get(ctx, dest, d.rebindModel(m.Select()), m.GetID()); err != nil {
+		return err
+	}
+
+	v.Elem().Set(fresh.Elem())
+	return nil
 }
 
 // Insert inserts the given model in the database.
-func (d *DB) Insert(ctx context.Context, arg Model) error {
+func (d *DB) Insert(ctx context.Context, arg Model) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "insert", arg, "", arg.Insert())
+	_, _, err = d.insert(ctx, arg, arg.Insert())
+	return err
+}
+
+// InsertAudited inserts the given model in the database and returns the query
+// and the arguments used, so they can be persisted in an audit log. The query
+// is the one sent to the database, with the named parameters bound to the
+// driver's bind type.
+func (d *DB) InsertAudited(ctx context.Context, arg Model) (query string, args []any, err error) {
+	defer wrapQueryError(d.queryErrors, &err, "insert", arg, "", arg.Insert())
+	return d.insert(ctx, arg, arg.Insert())
+}
+
+// insert inserts the given model in the database using the given named insert
+// query.
+func (d *DB) insert(ctx context.Context, arg Model, insertQuery string) (string, []any, error) {
+	if d.readOnly {
+		return "", nil, ErrReadOnly
+	}
+	if err := validate(arg); err != nil {
+		return "", nil, err
+	}
 	var id string
 	t0 := d.clock.Now()
 	arg.SetCreatedAt(t0)
 	arg.SetUpdatedAt(t0)
 
-	query, qargs, err := d.db.BindNamed(arg.Insert(), arg)
+	query, qargs, err := d.db.BindNamed(insertQuery, arg)
 	if err != nil {
-		return err
+		return "", nil, err
+	}
+
+	ctx, ex, release, err := d.acquire(ctx, d.writeTimeout)
+	if err != nil {
+		return "", nil, err
 	}
+	defer release()
 
 	// Do insert using an exec if necessary.
 	if _, ok := arg.(ModelWithExecInsert); ok {
-		return d.insertWithExec(ctx, query, qargs...)
+		err := insertWithExec(ctx, ex, query, qargs...)
+		if d.newID != nil && isPrimaryKeyViolation(err) {
+			if _, inTx := TxFromContext(ctx); !inTx {
+				for i := 0; i < d.idRetries && isPrimaryKeyViolation(err); i++ {
+					arg.SetID(d.newID())
+					if query, qargs, err = d.db.BindNamed(insertQuery, arg); err != nil {
+						return "", nil, err
+					}
+					err = insertWithExec(ctx, ex, query, qargs...)
+				}
+			}
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		return query, qargs, nil
 	}
 
-	row := d.db.QueryRowContext(ctx, query, qargs...)
+	row := ex.QueryRowContext(ctx, query, qargs...)
 	if err := row.Scan(&id); err != nil {
-		return err
+		return "", nil, err
 	}
 	arg.SetID(id)
-	return nil
+	return query, qargs, nil
 }
 
-func (d *DB) insertWithExec(ctx context.Context, query string, args ...any) error {
-	r, err := d.db.ExecContext(ctx, query, args...)
+func insertWithExec(ctx context.Context, ex executor, query string, args ...any) error {
+	r, err := ex.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
 	return RowsAffected(r, 1)
 }
 
-// InsertBatch inserts the given modules in a database using a transaction.
-func (d *DB) InsertBatch(ctx context.Context, args []Model) error {
-	t0 := d.clock.Now()
-
-	tx, err := d.db.BeginTxx(ctx, nil)
+// InsertDefaults inserts a row with the default values of all the columns,
+// `INSERT INTO table DEFAULT VALUES`, in the table of the given model, and
+// populates the model with the returned row, including the id. It is useful for
+// tables where the database fills every column, like a sequence of ticket
+// numbers.
+func (d *DB) InsertDefaults(ctx context.Context, arg Model) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "insert", arg, "", "")
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	b, err := queryBuilder(arg)
 	if err != nil {
 		return err
 	}
+	query := fmt.Sprintf("INSERT INTO %s DEFAULT VALUES RETURNING %s", b.Table, strings.Join(b.Columns, ", "))
+	return d.get(ctx, d.writeTimeout, arg, query)
+}
+
+// InsertBatch inserts the given modules in a database using a transaction, see
+// RunInTx. If the context is canceled, the remaining inserts are aborted and the
+// transaction is rolled back.
+func (d *DB) InsertBatch(ctx context.Context, args []Model) (err error) {
+	// current is the model being inserted, if any
+	var current Model
 	defer func() {
-		_ = tx.Rollback()
+		var query string
+		if current != nil {
+			query = current.Insert()
+		}
+		wrapQueryError(d.queryErrors, &err, "insert batch", current, "", query)
 	}()
 
-	var id string
+	if d.readOnly {
+		return ErrReadOnly
+	}
 	for _, a := range args {
-		a.SetCreatedAt(t0)
-		a.SetUpdatedAt(t0)
-		query, qargs, err := tx.BindNamed(a.Insert(), a)
-		if err != nil {
+		current = a
+		if err := validate(a); err != nil {
 			return err
 		}
-		if _, ok := a.(ModelWithExecInsert); ok {
-			r, err := tx.Exec(query, qargs...)
+	}
+	current = nil
+	t0 := d.clock.Now()
+
+	return d.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+		var id string
+		for _, a := range args {
+			current = a
+			a.SetCreatedAt(t0)
+			a.SetUpdatedAt(t0)
+			query, qargs, err := tx.tx.BindNamed(a.Insert(), a)
 			if err != nil {
 				return err
 			}
-			if err := RowsAffected(r, 1); err != nil {
-				return err
+			query = tx.track(tagQuery(ctx, query))
+			if _, ok := a.(ModelWithExecInsert); ok {
+				r, err := tx.tx.ExecContext(ctx, query, qargs...)
+				if err != nil {
+					return err
+				}
+				if err := RowsAffected(r, 1); err != nil {
+					return err
+				}
+			} else {
+				row := tx.tx.QueryRowContext(ctx, query, qargs...)
+				if err := row.Scan(&id); err != nil {
+					return err
+				}
+				a.SetID(id)
 			}
-		} else {
-			row := tx.QueryRow(query, qargs...)
-			if err := row.Scan(&id); err != nil {
-				return err
-			}
-			a.SetID(id)
 		}
-	}
+		current = nil
+		return nil
+	})
+}
 
-	return tx.Commit()
+// InsertBatchIndexed inserts the given models like InsertBatch and returns them
+// in a map keyed by their ids, the ones generated by the database or, for
+// models implementing ModelWithExecInsert, the ones already set.
+func (d *DB) InsertBatchIndexed(ctx context.Context, args []Model) (map[string]Model, error) {
+	if err := d.InsertBatch(ctx, args); err != nil {
+		return nil, err
+	}
+	m := make(map[string]Model, len(args))
+	for _, a := range args {
+		m[a.GetID()] = a
+	}
+	return m, nil
 }
 
 // Update updates the given model in the datastore.
-func (d *DB) Update(ctx context.Context, arg Model) error {
+func (d *DB) Update(ctx context.Context, arg Model) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "update", arg, "", arg.Update())
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	if err := validate(arg); err != nil {
+		return err
+	}
 	arg.SetUpdatedAt(d.clock.Now())
 	query, qargs, err := d.db.BindNamed(arg.Update(), arg)
 	if err != nil {
 		return err
 	}
-	r, err := d.db.ExecContext(ctx, query, qargs...)
+	r, err := d.Exec(ctx, query, qargs...)
 	if err != nil {
 		return err
 	}
 	return RowsAffected(r, 1)
 }
 
+// UpdateIfChanged updates the given model like Update, but only if any of its
+// columns differs from the row in the database, so updated_at is only set on
+// actual changes. It returns true if the row was updated; if it was not, the
+// model is populated with the row in the database, restoring its updated_at.
+//
+// The comparison is done by the database, with `IS DISTINCT FROM`, on all the
+// columns of the model but the primary key, created_at, updated_at, and the
+// generated columns. These columns must have an equality operator, json
+// columns, for example, do not, use jsonb instead. UpdateIfChanged fails if the
+// model has no columns to compare, or if its Update method does not return the
+// default query, see [Queries] and [QueriesWithGenerated], as a custom query
+// cannot be combined with the comparison.
+func (d *DB) UpdateIfChanged(ctx context.Context, arg Model) (updated bool, err error) {
+	defer wrapQueryError(d.queryErrors, &err, "update", arg, "", "")
+	if d.readOnly {
+		return false, ErrReadOnly
+	}
+	if err := validate(arg); err != nil {
+		return false, err
+	}
+	b, err := queryBuilder(arg)
+	if err != nil {
+		return false, err
+	}
+	updateQuery, err := updateIfChangedQuery(b, arg)
+	if err != nil {
+		return false, err
+	}
+
+	arg.SetUpdatedAt(d.clock.Now())
+	query, qargs, err := d.db.BindNamed(updateQuery, arg)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, ex, release, err := d.acquire(ctx, d.writeTimeout)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	r, err := ex.ExecContext(ctx, query, qargs...)
+	if err != nil {
+		return false, err
+	}
+	n, err := r.RowsAffected()
+	if err != nil || n == 1 {
+		return n == 1, err
+	}
+
+	// The row is unchanged or it does not exist. Like Update, the row can be
+	// soft-deleted.
+	selectQuery := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", strings.Join(b.Columns, ", "), b.Table, b.PrimaryKey)
+	return false, ex.GetContext(ctx, arg, d.Rebind(selectQuery), arg.GetID())
+}
+
+// updateIfChangedQuery returns the update query used by UpdateIfChanged, with
+// named parameters, that only updates the row if any of the compared columns
+// differs from the model.
+func updateIfChangedQuery(b *qb.QueryBuilder, arg Model) (string, error) {
+	generated := generatedColumns(arg)
+	if arg.Update() != writeBuilder(b, generated).NamedUpdate() {
+		return "", fmt.Errorf("custom update query of %T is not supported", arg)
+	}
+
+	var set, columns, values []string
+	for _, c := range b.Columns {
+		if c == b.PrimaryKey || c == "created_at" || c == "updated_at" || slices.Contains(generated, c) {
+			continue
+		}
+		set = append(set, c+" = :"+c)
+		columns = append(columns, c)
+		values = append(values, ":"+c)
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("model %T has no columns to compare", arg)
+	}
+	return fmt.Sprintf("UPDATE %s SET %s, updated_at = :updated_at WHERE %s = :%s AND (%s) IS DISTINCT FROM (%s)",
+		b.Table, strings.Join(set, ", "), b.PrimaryKey, b.PrimaryKey, strings.Join(columns, ", "), strings.Join(values, ", ")), nil
+}
+
+// setClause returns the SET clause, using `?` placeholders, and its arguments
+// to update the columns in changes and the updated_at column to t0. It fails
+// if any column is not a column of the model, or it cannot be updated.
+func setClause(b *qb.QueryBuilder, arg Model, changes map[string]any, t0 time.Time) (string, []any, error) {
+	generated := generatedColumns(arg)
+	columns := make([]string, 0, len(changes))
+	for column := range changes {
+		switch {
+		case column == b.PrimaryKey || column == "updated_at" || slices.Contains(generated, column):
+			return "", nil, fmt.Errorf("column %q cannot be updated", column)
+		case !hasColumn(b, column):
+			return "", nil, fmt.Errorf("column %q not found in %s", column, b.Table)
+		}
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	set := make([]string, 0, len(columns)+1)
+	args := make([]any, 0, len(columns)+1)
+	for _, column := range columns {
+		set = append(set, column+" = ?")
+		args = append(args, changes[column])
+	}
+	set = append(set, "updated_at = ?")
+	args = append(args, t0)
+	return strings.Join(set, ", "), args, nil
+}
+
+// UpdateMap updates the columns in changes, keyed by column name, of the row
+// of the given model, and sets its updated_at column to the current date. It
+// is useful to apply partial updates, like the body of a PATCH request, without
+// loading the model first. Only the updated_at field of the model is modified,
+// use Reload to get the updated row.
+//
+// UpdateMap fails if any key in changes is not a column of the model, or if it
+// is the primary key, updated_at, or a generated column.
+func (d *DB) UpdateMap(ctx context.Context, arg Model, changes map[string]any) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "update", arg, "", "")
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	b, err := queryBuilder(arg)
+	if err != nil {
+		return err
+	}
+
+	t0 := d.clock.Now()
+	set, args, err := setClause(b, arg, changes, t0)
+	if err != nil {
+		return err
+	}
+	args = append(args, arg.GetID())
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", b.Table, set, b.PrimaryKey)
+	r, err := d.Exec(ctx, d.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	if err := RowsAffected(r, 1); err != nil {
+		return err
+	}
+
+	arg.SetUpdatedAt(t0)
+	return nil
+}
+
+// UpdateWhereReturning updates the columns in set, keyed by column name, and
+// the updated_at column of all the rows in the table of the model m matching
+// the where condition, and populates dest, a pointer to a slice, with the
+// updated rows. Soft-deleted rows are not updated. The where condition can use
+// `?` placeholders for the given args. For example:
+//
+//	var users []*User
+//	err := db.UpdateWhereReturning(ctx, &users, &User{},
+//		map[string]any{"status": "inactive"}, "last_login < ?", cutoff)
+//
+// As with UpdateMap, it fails if any key in set is not a column of the model,
+// or if it is the primary key, updated_at, or a generated column.
+func (d *DB) UpdateWhereReturning(ctx context.Context, dest any, m Model, set map[string]any, where string, args ...any) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "update", m, "", "")
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	b, err := queryBuilder(m)
+	if err != nil {
+		return err
+	}
+	setSQL, setArgs, err := setClause(b, m, set, d.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE (%s) AND deleted_at IS NULL RETURNING %s",
+		b.Table, setSQL, where, strings.Join(b.Columns, ", "))
+	ctx, ex, release, err := d.acquire(ctx, d.writeTimeout)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return ex.SelectContext(ctx, dest, d.Rebind(query), append(setArgs, args...)...)
+}
+
 // Delete soft-deletes the given model in the database setting the deleted_at
 // column to the current date.
-func (d *DB) Delete(ctx context.Context, arg Model) error {
+func (d *DB) Delete(ctx context.Context, arg Model) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "delete", arg, "", arg.Delete())
+	if d.readOnly {
+		return ErrReadOnly
+	}
 	t0 := d.clock.Now()
-	r, err := d.db.ExecContext(ctx, d.rebindModel(arg.Delete()), t0, arg.GetID())
+	r, err := d.Exec(ctx, d.rebindModel(arg.Delete()), t0, arg.GetID())
 	if err != nil {
 		return err
 	}
@@ -393,15 +1616,82 @@ func (d *DB) Delete(ctx context.Context, arg Model) error {
 	return nil
 }
 
+// DeleteReturning soft-deletes the given model in the database, like Delete,
+// and populates the model with the row returned by the database, including the
+// deleted_at column. It returns sql.ErrNoRows if the row does not exist.
+func (d *DB) DeleteReturning(ctx context.Context, arg Model) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "delete", arg, "", arg.Delete())
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	b, err := queryBuilder(arg)
+	if err != nil {
+		return err
+	}
+	query := d.rebindModel(arg.Delete()) + " RETURNING " + strings.Join(b.Columns, ", ")
+	return d.get(ctx, d.writeTimeout, arg, query, d.clock.Now(), arg.GetID())
+}
+
 // HardDelete deletes the given model from the database.
-func (d *DB) HardDelete(ctx context.Context, arg ModelWithHardDelete) error {
-	r, err := d.db.ExecContext(ctx, d.rebindModel(arg.HardDelete()), arg.GetID())
+func (d *DB) HardDelete(ctx context.Context, arg ModelWithHardDelete) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "hard delete", arg, "", arg.HardDelete())
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	r, err := d.Exec(ctx, d.rebindModel(arg.HardDelete()), arg.GetID())
 	if err != nil {
 		return err
 	}
 	return RowsAffected(r, 1)
 }
 
+// DeleteByIDs soft-deletes the rows in the table of the model m with the given
+// ids, `WHERE id = ANY(ids)`, and returns the number of rows deleted. Rows
+// already soft-deleted are not modified or counted. If ids is empty, it returns
+// 0 without querying the database.
+func (d *DB) DeleteByIDs(ctx context.Context, m Model, ids []string) (n int64, err error) {
+	defer wrapQueryError(d.queryErrors, &err, "delete", m, "", "")
+	if d.readOnly {
+		return 0, ErrReadOnly
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	b, err := queryBuilder(m)
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf("UPDATE %s SET deleted_at = ? WHERE %s = ANY(?) AND deleted_at IS NULL", b.Table, b.PrimaryKey)
+	r, err := d.Exec(ctx, d.Rebind(query), d.clock.Now(), Array[string](ids))
+	if err != nil {
+		return 0, err
+	}
+	return r.RowsAffected()
+}
+
+// HardDeleteByIDs deletes the rows in the table of the model m with the given
+// ids, `WHERE id = ANY(ids)`, and returns the number of rows deleted. If ids is
+// empty, it returns 0 without querying the database.
+func (d *DB) HardDeleteByIDs(ctx context.Context, m Model, ids []string) (n int64, err error) {
+	defer wrapQueryError(d.queryErrors, &err, "hard delete", m, "", "")
+	if d.readOnly {
+		return 0, ErrReadOnly
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	b, err := queryBuilder(m)
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ANY(?)", b.Table, b.PrimaryKey)
+	r, err := d.Exec(ctx, d.Rebind(query), Array[string](ids))
+	if err != nil {
+		return 0, err
+	}
+	return r.RowsAffected()
+}
+
 // Prepare creates a prepared statement.
 func (d *DB) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
 	return d.db.PrepareContext(ctx, query)
@@ -412,19 +1702,77 @@ type Tx struct {
 	tx            *sqlx.Tx
 	clock         clock.Clock
 	doRebindModel bool
+	queryErrors   bool
+	savepoints    int
+	tracker       *queryTracker
+	observer      TxObserver
+	started       time.Time
+	endOnce       sync.Once
+	name          string
+	openTxs       *atomic.Int64
+	ended         atomic.Bool
 }
 
 // Begin begins a transaction and returns a new Tx.
 func (d *DB) Begin(ctx context.Context) (*Tx, error) {
-	tx, err := d.db.BeginTxx(ctx, nil)
+	if d.readOnly {
+		return nil, ErrReadOnly
+	}
+	return d.beginTx(ctx, nil)
+}
+
+// BeginNamed begins a transaction with the given name and returns a new Tx. The
+// name is prepended to all the queries in the transaction as an SQL comment,
+// `/* tx:name */ SELECT ...`, so the transaction can be identified in the
+// traces and in the database logs. The name is sanitized like the tags in
+// [WithQueryTag].
+func (d *DB) BeginNamed(ctx context.Context, name string) (*Tx, error) {
+	tx, err := d.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &Tx{
+	tx.name = sanitizeQueryTag(name)
+	return tx, nil
+}
+
+// OpenTransactions returns the number of transactions begun with the DB, or
+// with its connections, that have not been committed or rolled back yet. A
+// number that keeps growing indicates a transaction leak, that will eventually
+// exhaust the connection pool.
+func (d *DB) OpenTransactions() int {
+	return int(d.openTxs.Load())
+}
+
+// BeginReadOnly begins a read-only transaction and returns a new Tx. Any write
+// in the transaction will be rejected by the database.
+func (d *DB) BeginReadOnly(ctx context.Context) (*Tx, error) {
+	return d.beginTx(ctx, &sql.TxOptions{ReadOnly: true})
+}
+
+func (d *DB) beginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	ctx, ex, release, err := d.acquireConn(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseLater(release)
+
+	tx, err := ex.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	d.openTxs.Add(1)
+	t := &Tx{
 		tx:            tx,
 		clock:         d.clock,
 		doRebindModel: d.doRebindModel,
-	}, nil
+		queryErrors:   d.queryErrors,
+		tracker:       newQueryTracker(d.trackQueries),
+		observer:      d.txObserver,
+		started:       d.clock.Now(),
+		openTxs:       &d.openTxs,
+	}
+	d.leakDetector.watch(t)
+	return t, nil
 }
 
 // Rebind transforms a query from QUESTION to the DB driver's bind type.
@@ -441,18 +1789,46 @@ func (t *Tx) rebindModel(query string) string {
 
 // Commit commits the transaction.
 func (t *Tx) Commit() error {
-	return t.tx.Commit()
+	return t.end(true, t.tx.Commit)
 }
 
 // Rollback aborts the transaction.
 func (t *Tx) Rollback() error {
-	return t.tx.Rollback()
+	return t.end(false, t.tx.Rollback)
+}
+
+// Underlying returns the underlying *sqlx.Tx, for interoperability with
+// libraries that require a *sqlx.Tx or a *sql.Tx, available in its Tx field.
+// The transaction must be committed or rolled back with the methods of the Tx,
+// never with the underlying one.
+func (t *Tx) Underlying() *sqlx.Tx {
+	return t.tx
+}
+
+// CommitContext commits the transaction, or, if the context is already done,
+// rolls it back and returns the context error. Once started, the commit is
+// not interrupted and its result is always returned, so an error means that
+// the transaction was not committed. To bound the commit, use a context with a
+// deadline in Begin, the database aborts the transaction if it is done.
+func (t *Tx) CommitContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		_ = t.Rollback()
+		return err
+	}
+	return t.Commit()
+}
+
+// RollbackContext aborts the transaction. As with CommitContext, the rollback
+// is not interrupted if the context is done, and its result is always
+// returned.
+func (t *Tx) RollbackContext(ctx context.Context) error {
+	return t.Rollback()
 }
 
 // Query executes a query that returns rows, typically a SELECT. The args are
 // for any placeholder parameters in the query.
 func (t *Tx) Query(query string, args ...any) (*sql.Rows, error) {
-	return t.tx.Query(query, args...)
+	return t.tx.Query(t.track(query), args...)
 }
 
 // QueryRow executes a query that is expected to return at most one row.
@@ -463,20 +1839,32 @@ func (t *Tx) Query(query string, args ...any) (*sql.Rows, error) {
 // Otherwise, the *Row's Scan scans the first selected row and discards the
 // rest.
 func (t *Tx) QueryRow(query string, args ...any) *sql.Row {
-	return t.tx.QueryRow(query, args...)
+	return t.tx.QueryRow(t.track(query), args...)
 }
 
 // Exec executes a query without returning any rows. The args are for any
 // placeholder parameters in the query.
 func (t *Tx) Exec(query string, args ...any) (sql.Result, error) {
-	return t.tx.Exec(query, args...)
+	return t.tx.Exec(t.track(query), args...)
+}
+
+// ExecExpect executes a query without returning any rows, like Exec, and
+// checks that it affected exactly want rows, see RowsAffected. It returns
+// sql.ErrNoRows if no rows were affected, or an error with the number of rows
+// if it differs from want.
+func (t *Tx) ExecExpect(query string, want int64, args ...any) error {
+	r, err := t.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	return RowsAffected(r, want)
 }
 
 // Query executes a query that returns rows, typically a SELECT. The query is
 // rebound from `?` to the DB driver's bind type. The args are for any
 // placeholder parameters in the query.
 func (t *Tx) RebindQuery(query string, args ...any) (*sql.Rows, error) {
-	return t.tx.Query(t.tx.Rebind(query), args...)
+	return t.tx.Query(t.track(t.tx.Rebind(query)), args...)
 }
 
 // QueryRow executes a query that is expected to return at most one row. The
@@ -488,40 +1876,60 @@ func (t *Tx) RebindQuery(query string, args ...any) (*sql.Rows, error) {
 // Otherwise, the *Row's Scan scans the first selected row and discards the
 // rest.
 func (t *Tx) RebindQueryRow(query string, args ...any) *sql.Row {
-	return t.tx.QueryRow(t.tx.Rebind(query), args...)
+	return t.tx.QueryRow(t.track(t.tx.Rebind(query)), args...)
 }
 
 // Exec executes a query without returning any rows. The query is rebound from
 // `?` to the DB driver's bind type. The args are for any placeholder parameters
 // in the query.
 func (t *Tx) RebindExec(query string, args ...any) (sql.Result, error) {
-	return t.tx.Exec(t.tx.Rebind(query), args...)
+	return t.tx.Exec(t.track(t.tx.Rebind(query)), args...)
 }
 
 // NamedQuery executes a query that returns rows. Any named placeholder
 // parameters are replaced with fields from arg.
 func (t *Tx) NamedQuery(query string, arg any) (*sqlx.Rows, error) {
-	return t.tx.NamedQuery(query, arg)
+	return t.tx.NamedQuery(t.track(query), arg)
 }
 
 // NamedExec using executes a query without returning any rows. Any named
 // placeholder parameters are replaced with fields from arg.
 func (t *Tx) NamedExec(query string, arg any) (sql.Result, error) {
-	return t.tx.NamedExec(query, arg)
+	return t.tx.NamedExec(t.track(query), arg)
 }
 
 // Select populates the given model with the result of a select by id query.
-func (t *Tx) Select(dest Model, id string) error {
-	return t.tx.Get(dest, t.rebindModel(dest.Select()), id)
+func (t *Tx) Select(dest Model, id string) (err error) {
+	defer wrapQueryError(t.queryErrors, &err, "select", dest, "", dest.Select())
+	return t.tx.Get(dest, t.track(t.rebindModel(dest.Select())), id)
 }
 
-// Get populates the given model for the result of the given select query.
-func (t *Tx) Get(dest Model, query string, args ...any) error {
-	return t.tx.Get(dest, query, args...)
+// Get populates the given destination with the result of the given select
+// query. The destination is usually a model, but it can be a pointer to any
+// struct with db tags or to a scannable value.
+func (t *Tx) Get(dest any, query string, args ...any) error {
+	return t.tx.Get(dest, t.track(query), args...)
 }
 
 // Insert adds a new insert query for the given model in the transaction.
-func (t *Tx) Insert(arg Model) error {
+func (t *Tx) Insert(arg Model) (err error) {
+	defer wrapQueryError(t.queryErrors, &err, "insert", arg, "", arg.Insert())
+	_, _, err = t.insert(arg)
+	return err
+}
+
+// InsertAudited adds a new insert query for the given model in the transaction
+// and returns the query and the arguments used, so they can be persisted in an
+// audit log in the same transaction.
+func (t *Tx) InsertAudited(arg Model) (query string, args []any, err error) {
+	defer wrapQueryError(t.queryErrors, &err, "insert", arg, "", arg.Insert())
+	return t.insert(arg)
+}
+
+func (t *Tx) insert(arg Model) (string, []any, error) {
+	if err := validate(arg); err != nil {
+		return "", nil, err
+	}
 	var id string
 	t0 := t.clock.Now()
 	arg.SetCreatedAt(t0)
@@ -529,25 +1937,40 @@ func (t *Tx) Insert(arg Model) error {
 
 	query, qargs, err := t.tx.BindNamed(arg.Insert(), arg)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
 	// Do insert using an exec if necessary.
 	if _, ok := arg.(ModelWithExecInsert); ok {
-		return t.insertWithExec(query, qargs...)
+		if err := t.insertWithExec(query, qargs...); err != nil {
+			return "", nil, err
+		}
+		return query, qargs, nil
 	}
 
 	// Insert query with 'RETURNING id'
-	row := t.tx.QueryRow(query, qargs...)
+	row := t.tx.QueryRow(t.track(query), qargs...)
 	if err := row.Scan(&id); err != nil {
-		return err
+		return "", nil, err
 	}
 	arg.SetID(id)
+	return query, qargs, nil
+}
+
+// InsertAll adds a new insert query for each one of the given models in the
+// transaction. The models are inserted in order, and the models can be of
+// different types. It stops on the first error.
+func (t *Tx) InsertAll(args ...Model) error {
+	for _, a := range args {
+		if err := t.Insert(a); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (t *Tx) insertWithExec(query string, args ...any) error {
-	r, err := t.tx.Exec(query, args...)
+	r, err := t.tx.Exec(t.track(query), args...)
 	if err != nil {
 		return err
 	}
@@ -555,13 +1978,17 @@ func (t *Tx) insertWithExec(query string, args ...any) error {
 }
 
 // Update adds a new update query for the given model in the transaction.
-func (t *Tx) Update(arg Model) error {
+func (t *Tx) Update(arg Model) (err error) {
+	defer wrapQueryError(t.queryErrors, &err, "update", arg, "", arg.Update())
+	if err := validate(arg); err != nil {
+		return err
+	}
 	arg.SetUpdatedAt(t.clock.Now())
 	query, qargs, err := t.tx.BindNamed(arg.Update(), arg)
 	if err != nil {
 		return err
 	}
-	r, err := t.tx.Exec(query, qargs...)
+	r, err := t.tx.Exec(t.track(query), qargs...)
 	if err != nil {
 		return err
 	}
@@ -569,9 +1996,10 @@ func (t *Tx) Update(arg Model) error {
 }
 
 // Delete adds a new soft-delete query in the transaction.
-func (t *Tx) Delete(arg Model) error {
+func (t *Tx) Delete(arg Model) (err error) {
+	defer wrapQueryError(t.queryErrors, &err, "delete", arg, "", arg.Delete())
 	t0 := t.clock.Now()
-	r, err := t.tx.Exec(t.rebindModel(arg.Delete()), t0, arg.GetID())
+	r, err := t.tx.Exec(t.track(t.rebindModel(arg.Delete())), t0, arg.GetID())
 	if err != nil {
 		return err
 	}
@@ -584,8 +2012,9 @@ func (t *Tx) Delete(arg Model) error {
 }
 
 // HardDelete ads a new hard-delete query in the transaction.
-func (t *Tx) HardDelete(arg ModelWithHardDelete) error {
-	r, err := t.tx.Exec(t.rebindModel(arg.HardDelete()), arg.GetID())
+func (t *Tx) HardDelete(arg ModelWithHardDelete) (err error) {
+	defer wrapQueryError(t.queryErrors, &err, "hard delete", arg, "", arg.HardDelete())
+	r, err := t.tx.Exec(t.track(t.rebindModel(arg.HardDelete())), arg.GetID())
 	if err != nil {
 		return err
 	}
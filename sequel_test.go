@@ -2,14 +2,18 @@ package sequel
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/go-sqlx/sqlx"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/stretchr/testify/assert"
@@ -121,10 +125,31 @@ func TestNew(t *testing.T) {
 	}{
 		{"ok", args{postgresDataSource, nil}, assert.NoError},
 		{"ok with clock", args{postgresDataSource, []Option{WithClock(clock.NewMock(time.Now()))}}, assert.NoError},
+		{"ok with nowFunc", args{postgresDataSource, []Option{WithNowFunc(time.Now)}}, assert.NoError},
 		{"ok with driver", args{postgresDataSource, []Option{WithDriver("pgx/v5")}}, assert.NoError},
 		{"ok with rebindModel", args{postgresDataSource, []Option{WithRebindModel()}}, assert.NoError},
 		{"ok with maxConnections", args{postgresDataSource, []Option{WithMaxOpenConnections(10)}}, assert.NoError},
+		{"ok with acquireTimeout", args{postgresDataSource, []Option{WithAcquireTimeout(time.Second)}}, assert.NoError},
+		{"ok with readOnly", args{postgresDataSource, []Option{WithReadOnly()}}, assert.NoError},
+		{"ok with queryTimeout", args{postgresDataSource, []Option{WithQueryTimeout(time.Second)}}, assert.NoError},
+		{"ok with readTimeout and writeTimeout", args{postgresDataSource, []Option{WithReadTimeout(time.Second), WithWriteTimeout(time.Minute)}}, assert.NoError},
+		{"ok with statementCache", args{postgresDataSource, []Option{WithStatementCache(16)}}, assert.NoError},
+		{"ok with queryErrors", args{postgresDataSource, []Option{WithQueryErrors()}}, assert.NoError},
+		{"ok with retryOnConnError", args{postgresDataSource, []Option{WithRetryOnConnError(2)}}, assert.NoError},
+		{"ok with warmup", args{postgresDataSource, []Option{WithWarmup(4)}}, assert.NoError},
+		{"ok with cancelRequest", args{postgresDataSource, []Option{WithCancelRequest(time.Second)}}, assert.NoError},
+		{"ok with statementTimeout", args{postgresDataSource, []Option{WithStatementTimeout(time.Second)}}, assert.NoError},
+		{"ok with lazyConnect", args{postgresDataSource, []Option{WithLazyConnect()}}, assert.NoError},
+		{"ok lazyConnect without ping", args{strings.ReplaceAll(postgresDataSource, dbUser, "foo"), []Option{WithLazyConnect(), WithWarmup(2)}}, assert.NoError},
+		{"ok lazyConnect without ping with statementCache", args{strings.ReplaceAll(postgresDataSource, dbUser, "foo"), []Option{WithLazyConnect(), WithStatementCache(16)}}, assert.NoError},
 		{"fail ping", args{strings.ReplaceAll(postgresDataSource, dbUser, "foo"), nil}, assert.Error},
+		{"fail ping with statementCache", args{strings.ReplaceAll(postgresDataSource, dbUser, "foo"), []Option{WithStatementCache(16)}}, assert.Error},
+		{"fail statementCache driver", args{postgresDataSource, []Option{WithDriver("postgres"), WithStatementCache(16)}}, assert.Error},
+		{"fail statementCache dataSource", args{"foo=bar", []Option{WithStatementCache(16)}}, assert.Error},
+		{"fail tlsConfig driver", args{postgresDataSource, []Option{WithDriver("postgres"), WithTLSConfig(&tls.Config{})}}, assert.Error},
+		{"fail cancelRequest driver", args{postgresDataSource, []Option{WithDriver("postgres"), WithCancelRequest(time.Second)}}, assert.Error},
+		{"fail statementTimeout driver", args{postgresDataSource, []Option{WithDriver("postgres"), WithStatementTimeout(time.Second)}}, assert.Error},
+		{"fail tlsConfig without server support", args{postgresDataSource, []Option{WithTLSConfig(&tls.Config{InsecureSkipVerify: true})}}, assert.Error},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -137,6 +162,29 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestDB_lazyConnect(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := New(strings.ReplaceAll(postgresDataSource, dbUser, "foo"), WithLazyConnect())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	assert.Equal(t, 0, db.DB().Stats().OpenConnections)
+	var n int
+	assert.Error(t, db.Get(ctx, &n, "SELECT 1"))
+
+	db, err = New(postgresDataSource, WithLazyConnect())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	assert.Equal(t, 0, db.DB().Stats().OpenConnections)
+	assert.NoError(t, db.Get(ctx, &n, "SELECT 1"))
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, db.DB().Stats().OpenConnections)
+}
+
 func TestNewDB(t *testing.T) {
 	testTime := time.Now()
 
@@ -170,6 +218,12 @@ func TestNewDB(t *testing.T) {
 			driverName:    "pgx",
 		}, assert.NoError},
 		{"fail ping", args{closedDB, "pgx/v5", nil}, nil, assert.Error},
+		{"ok lazyConnect", args{closedDB, "pgx/v5", []Option{WithLazyConnect()}}, &DB{
+			db:            sqlx.NewDb(closedDB, "pgx/v5"),
+			clock:         clock.New(),
+			doRebindModel: false,
+			driverName:    "pgx/v5",
+		}, assert.NoError},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -230,6 +284,8 @@ func TestIsUniqueViolation(t *testing.T) {
 		{"true", args{&pgconn.PgError{Code: "23505"}}, true},
 		{"false", args{&pgconn.PgError{Code: "10000"}}, false},
 		{"false other", args{sql.ErrNoRows}, false},
+		{"true other driver", args{&sqlStateErr{"23505"}}, true},
+		{"false other driver", args{&sqlStateErr{"23503"}}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -238,6 +294,102 @@ func TestIsUniqueViolation(t *testing.T) {
 	}
 }
 
+// sqlStateErr is an error of a driver other than pgx, like the lib/pq one.
+type sqlStateErr struct {
+	code string
+}
+
+func (e *sqlStateErr) Error() string    { return "pq: error " + e.code }
+func (e *sqlStateErr) SQLState() string { return e.code }
+
+func TestIsForeignKeyViolation(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"true", args{&pgconn.PgError{Code: "23503"}}, true},
+		{"true wrapped", args{fmt.Errorf("some error: %w", &pgconn.PgError{Code: "23503"})}, true},
+		{"true other driver", args{fmt.Errorf("some error: %w", &sqlStateErr{"23503"})}, true},
+		{"false unique violation", args{&pgconn.PgError{Code: "23505"}}, false},
+		{"false other driver", args{&sqlStateErr{"23505"}}, false},
+		{"false other", args{sql.ErrNoRows}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsForeignKeyViolation(tt.args.err))
+		})
+	}
+}
+
+func Test_isPrimaryKeyViolation(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"true", args{&pgconn.PgError{Code: "23505", TableName: "person_test", ConstraintName: "person_test_pkey"}}, true},
+		{"true wrapped", args{fmt.Errorf("some error: %w", &pgconn.PgError{Code: "23505", TableName: "person_test", ConstraintName: "person_test_pkey"})}, true},
+		{"false other constraint", args{&pgconn.PgError{Code: "23505", TableName: "person_test", ConstraintName: "person_test_email_idx"}}, false},
+		{"false no table", args{&pgconn.PgError{Code: "23505", ConstraintName: "_pkey"}}, false},
+		{"false other code", args{&pgconn.PgError{Code: "23503", TableName: "person_test", ConstraintName: "person_test_pkey"}}, false},
+		{"false other", args{sql.ErrNoRows}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isPrimaryKeyViolation(tt.args.err))
+		})
+	}
+}
+
+func TestIsDeadlock(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"true", args{&pgconn.PgError{Code: "40P01"}}, true},
+		{"true wrapped", args{fmt.Errorf("some error: %w", &pgconn.PgError{Code: "40P01"})}, true},
+		{"false serialization failure", args{&pgconn.PgError{Code: "40001"}}, false},
+		{"false other", args{sql.ErrNoRows}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsDeadlock(tt.args.err))
+		})
+	}
+}
+
+func TestIsSerializationFailure(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"true", args{&pgconn.PgError{Code: "40001"}}, true},
+		{"true wrapped", args{fmt.Errorf("some error: %w", &pgconn.PgError{Code: "40001"})}, true},
+		{"false deadlock", args{&pgconn.PgError{Code: "40P01"}}, false},
+		{"false other", args{sql.ErrNoRows}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsSerializationFailure(tt.args.err))
+		})
+	}
+}
+
 func TestDBQueries(t *testing.T) {
 	db, err := New(postgresDataSource)
 	require.NoError(t, err)
@@ -384,6 +536,26 @@ func TestDBQueries(t *testing.T) {
 		assertEqualPerson(t, &personModel{}, &pp2)
 	})
 
+	t.Run("get projection", func(t *testing.T) {
+		var projection struct {
+			ID   string `db:"id"`
+			Name string `db:"name"`
+		}
+		assert.NoError(t, db.Get(ctx, &projection, "SELECT id, name FROM person_test WHERE id = $1", p1.GetID()))
+		assert.Equal(t, p1.ID, projection.ID)
+		assert.Equal(t, p1.Name, projection.Name)
+
+		var name string
+		assert.NoError(t, db.Get(ctx, &name, "SELECT name FROM person_test WHERE id = $1", p2.GetID()))
+		assert.Equal(t, p2.Name, name)
+
+		var count int
+		assert.NoError(t, db.Get(ctx, &count, "SELECT count(*) FROM person_test"))
+		assert.Equal(t, 5, count)
+
+		assert.Error(t, db.Get(ctx, &projection, "SELECT * FROM person_test WHERE id = $1", p1.GetID()))
+	})
+
 	t.Run("getAll", func(t *testing.T) {
 		var ap []*personModel
 		assert.NoError(t, db.GetAll(ctx, &ap, "SELECT * FROM person_test"))
@@ -605,6 +777,24 @@ func TestTxQueries(t *testing.T) {
 		assert.NoError(t, tx.Commit())
 	})
 
+	t.Run("get projection", func(t *testing.T) {
+		var projection struct {
+			Name  string         `db:"name"`
+			Email sql.NullString `db:"email"`
+		}
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		err = tx.Get(&projection, "SELECT name, email FROM person_test WHERE id = $1", p1.GetID())
+		assert.NoError(t, err)
+		assert.Equal(t, p1.Name, projection.Name)
+		assert.Equal(t, p1.Email, projection.Email)
+
+		var name string
+		assert.NoError(t, tx.Get(&name, "SELECT name FROM person_test WHERE id = $1", p1.GetID()))
+		assert.Equal(t, p1.Name, name)
+		assert.NoError(t, tx.Commit())
+	})
+
 	t.Run("select", func(t *testing.T) {
 		var p personModel
 		tx, err := db.Begin(ctx)
@@ -911,3 +1101,1776 @@ func TestDB_DB(t *testing.T) {
 	assert.Equal(t, db, sdb.DB())
 	assert.NoError(t, sdb.Close())
 }
+
+func TestDB_namingStrategy(t *testing.T) {
+	snakeCase := func(s string) string {
+		var b strings.Builder
+		for i, r := range s {
+			if r >= 'A' && r <= 'Z' {
+				if i > 0 {
+					b.WriteByte('_')
+				}
+				r += 'a' - 'A'
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+
+	db, err := New(postgresDataSource, WithNamingStrategy(snakeCase))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.Insert(ctx, p1))
+
+	type person struct {
+		ID        string
+		Name      string
+		CreatedAt time.Time
+	}
+
+	var got person
+	assert.NoError(t, db.Get(ctx, &got, "SELECT id, name, created_at FROM person_test WHERE id = $1", p1.GetID()))
+	assert.Equal(t, p1.GetID(), got.ID)
+	assert.Equal(t, "Lucky Luke", got.Name)
+	assert.Equal(t, p1.CreatedAt.Truncate(time.Second), got.CreatedAt.UTC().Truncate(time.Second))
+
+	var all []person
+	assert.NoError(t, db.GetAll(ctx, &all, "SELECT id, name, created_at FROM person_test"))
+	assert.Equal(t, []person{got}, all)
+
+	all = nil
+	assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+		return tx.tx.Select(&all, "SELECT id, name, created_at FROM person_test")
+	}))
+	assert.Equal(t, []person{got}, all)
+
+	rows, err := db.NamedQuery(ctx, "SELECT name FROM person_test WHERE created_at = :created_at", person{CreatedAt: got.CreatedAt})
+	require.NoError(t, err)
+	assert.True(t, rows.Next())
+	assert.NoError(t, rows.Close())
+
+	// Tagged fields are not affected
+	var p personModel
+	assert.NoError(t, db.Select(ctx, &p, p1.GetID()))
+	assertEqualPerson(t, p1, &p)
+}
+
+func TestDB_warmup(t *testing.T) {
+	db, err := New(postgresDataSource, WithWarmup(5))
+	require.NoError(t, err)
+	stats := db.DB().Stats()
+	assert.Equal(t, 5, stats.OpenConnections)
+	assert.Equal(t, 5, stats.Idle)
+	assert.NoError(t, db.Close())
+
+	db, err = New(postgresDataSource, WithWarmup(5), WithMaxOpenConnections(3))
+	require.NoError(t, err)
+	assert.Equal(t, 3, db.DB().Stats().Idle)
+	assert.NoError(t, db.Close())
+
+	sqlDB, err := sql.Open("pgx/v5", postgresDataSource)
+	require.NoError(t, err)
+	db, err = NewDB(sqlDB, "pgx/v5", WithWarmup(2))
+	require.NoError(t, err)
+	assert.Equal(t, 2, db.DB().Stats().Idle)
+	assert.NoError(t, db.Close())
+}
+
+func TestDB_Underlying(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	assert.Same(t, db.db, db.Underlying())
+	assert.Same(t, db.DB(), db.Underlying().DB)
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	tx, err := db.Begin(ctx)
+	require.NoError(t, err)
+	assert.Same(t, tx.tx, tx.Underlying())
+
+	// Queries run with the underlying transaction are in the same transaction
+	p := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, tx.Insert(p))
+	var name string
+	assert.NoError(t, tx.Underlying().Tx.QueryRow("SELECT name FROM person_test WHERE id = $1", p.GetID()).Scan(&name))
+	assert.Equal(t, "Lucky Luke", name)
+	assert.NoError(t, tx.Rollback())
+	assert.ErrorIs(t, db.Select(ctx, &personModel{}, p.GetID()), sql.ErrNoRows)
+}
+
+func TestDB_PoolWaitStats(t *testing.T) {
+	db, err := New(postgresDataSource, WithMaxOpenConnections(1))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	count, total := db.PoolWaitStats()
+	assert.Equal(t, int64(0), count)
+	assert.Equal(t, time.Duration(0), total)
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		conn.Close()
+	}()
+
+	var n int
+	assert.NoError(t, db.Get(ctx, &n, "SELECT 1"))
+	count, total = db.PoolWaitStats()
+	assert.Equal(t, int64(1), count)
+	assert.GreaterOrEqual(t, total, 50*time.Millisecond)
+}
+
+func TestDB_acquireTimeout(t *testing.T) {
+	db, err := New(postgresDataSource, WithMaxOpenConnections(1), WithAcquireTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{
+		Name:  "Lucky Luke",
+		Email: NullString("lucky@example.com"),
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		var p personModel
+		assert.NoError(t, db.Insert(ctx, p1))
+		assert.NoError(t, db.Select(ctx, &p, p1.GetID()))
+		assertEqualPerson(t, p1, &p)
+	})
+
+	t.Run("exhausted by transaction", func(t *testing.T) {
+		var p personModel
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, db.Select(ctx, &p, p1.GetID()), ErrPoolExhausted)
+		_, err = db.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, ErrPoolExhausted)
+		_, err = db.Begin(ctx)
+		assert.ErrorIs(t, err, ErrPoolExhausted)
+		assert.ErrorIs(t, db.QueryRow(ctx, "SELECT 1").Err(), context.DeadlineExceeded)
+
+		assert.NoError(t, tx.Rollback())
+		assert.Eventually(t, func() bool {
+			return db.Select(ctx, &p, p1.GetID()) == nil
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("exhausted by rows", func(t *testing.T) {
+		var p personModel
+		rows, err := db.Query(ctx, "SELECT * FROM person_test")
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, db.Select(ctx, &p, p1.GetID()), ErrPoolExhausted)
+
+		assert.NoError(t, rows.Close()) //nolint:sqlclosecheck // no defer for testing purposes
+		assert.Eventually(t, func() bool {
+			return db.Select(ctx, &p, p1.GetID()) == nil
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := db.Exec(cctx, "SELECT 1")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.NotErrorIs(t, err, ErrPoolExhausted)
+
+		// The row has the error of the context, even if the pool is exhausted.
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		assert.ErrorIs(t, db.QueryRow(cctx, "SELECT 1").Err(), context.Canceled)
+	})
+
+	t.Run("exhausted with query timeout", func(t *testing.T) {
+		db, err := New(postgresDataSource, WithMaxOpenConnections(1),
+			WithAcquireTimeout(100*time.Millisecond), WithQueryTimeout(time.Second))
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		var n int
+		assert.ErrorIs(t, db.QueryRow(ctx, "SELECT 1").Scan(&n), context.DeadlineExceeded)
+	})
+
+	t.Run("exec (clear table)", func(t *testing.T) {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+}
+
+func TestDB_readOnly(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	rdb, err := New(postgresDataSource, WithReadOnly())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, rdb.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{
+		Name:  "Lucky Luke",
+		Email: NullString("lucky@example.com"),
+	}
+	p2 := &personModelExtra{
+		personModel: personModel{
+			Base: Base{
+				ID: "5b7e5b5c-3d5f-4b2b-9d8e-3b1f1c0e9a11",
+			},
+			Name:  "Jolly Jumper",
+			Email: NullString("jolly@example.com"),
+		},
+	}
+	require.NoError(t, db.Insert(ctx, p1))
+
+	t.Run("reads", func(t *testing.T) {
+		var p personModel
+		assert.NoError(t, rdb.Select(ctx, &p, p1.GetID()))
+		assertEqualPerson(t, p1, &p)
+
+		var ap []*personModel
+		assert.NoError(t, rdb.GetAll(ctx, &ap, "SELECT * FROM person_test"))
+		assertEqualPersons(t, []*personModel{p1}, ap)
+	})
+
+	t.Run("writes", func(t *testing.T) {
+		assert.ErrorIs(t, rdb.Insert(ctx, p2), ErrReadOnly)
+		assert.ErrorIs(t, rdb.InsertBatch(ctx, []Model{p2}), ErrReadOnly)
+		assert.ErrorIs(t, rdb.Update(ctx, p1), ErrReadOnly)
+		assert.ErrorIs(t, rdb.Delete(ctx, p1), ErrReadOnly)
+		assert.ErrorIs(t, rdb.HardDelete(ctx, p2), ErrReadOnly)
+		_, err := rdb.Exec(ctx, "DELETE FROM person_test")
+		assert.ErrorIs(t, err, ErrReadOnly)
+		_, err = rdb.RebindExec(ctx, "DELETE FROM person_test WHERE id = ?", p1.GetID())
+		assert.ErrorIs(t, err, ErrReadOnly)
+		_, err = rdb.NamedExec(ctx, "DELETE FROM person_test WHERE id = :id", p1)
+		assert.ErrorIs(t, err, ErrReadOnly)
+		_, err = rdb.Begin(ctx)
+		assert.ErrorIs(t, err, ErrReadOnly)
+	})
+
+	t.Run("beginReadOnly", func(t *testing.T) {
+		var p personModel
+		tx, err := rdb.BeginReadOnly(ctx)
+		require.NoError(t, err)
+		assert.NoError(t, tx.Select(&p, p1.GetID()))
+		assertEqualPerson(t, p1, &p)
+		assert.Error(t, tx.Insert(p2))
+		assert.NoError(t, tx.Rollback())
+	})
+
+	t.Run("exec (clear table)", func(t *testing.T) {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+}
+
+func TestDB_RawConn(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+
+	t.Run("ok", func(t *testing.T) {
+		var ap []*personModel
+		assert.NoError(t, db.RawConn(ctx, func(conn *pgx.Conn) error {
+			n, err := conn.CopyFrom(ctx, pgx.Identifier{"person_test"}, []string{"name", "email"}, pgx.CopyFromRows([][]any{
+				{"Lucky Luke", "lucky@example.com"},
+				{"Jolly Jumper", "jolly@example.com"},
+			}))
+			if err != nil {
+				return err
+			}
+			assert.Equal(t, int64(2), n)
+			return nil
+		}))
+		assert.NoError(t, db.GetAll(ctx, &ap, "SELECT * FROM person_test"))
+		assert.Len(t, ap, 2)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		assert.Error(t, db.RawConn(ctx, func(conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx, "SELECT * FROM missing_table")
+			return err
+		}))
+		assert.ErrorIs(t, db.RawConn(ctx, func(conn *pgx.Conn) error {
+			return sql.ErrNoRows
+		}), sql.ErrNoRows)
+	})
+
+	t.Run("exec (clear table)", func(t *testing.T) {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+}
+
+func TestDB_queryTimeout(t *testing.T) {
+	db, err := New(postgresDataSource, WithQueryTimeout(500*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{
+		Name:  "Lucky Luke",
+		Email: NullString("lucky@example.com"),
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		var p personModel
+		assert.NoError(t, db.Insert(ctx, p1))
+		assert.NoError(t, db.Select(ctx, &p, p1.GetID()))
+		assertEqualPerson(t, p1, &p)
+
+		rows, err := db.Query(ctx, "SELECT * FROM person_test")
+		require.NoError(t, err)
+		for rows.Next() {
+			assert.NoError(t, rows.Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt, &p.Name, &p.Email))
+			assertEqualPerson(t, p1, &p)
+		}
+		assert.NoError(t, rows.Err())
+		assert.NoError(t, rows.Close()) //nolint:sqlclosecheck // no defer for testing purposes
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := db.Exec(ctx, "SELECT pg_sleep(2)")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		var p personModel
+		assert.ErrorIs(t, db.Get(ctx, &p, "SELECT *, pg_sleep(2) FROM person_test"), context.DeadlineExceeded)
+	})
+
+	t.Run("shorter deadline", func(t *testing.T) {
+		cctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := db.Exec(cctx, "SELECT pg_sleep(2)")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		_, err = tx.Exec("SELECT pg_sleep(1)")
+		assert.NoError(t, err)
+		assert.NoError(t, tx.Commit())
+	})
+
+	t.Run("exec (clear table)", func(t *testing.T) {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+}
+
+func TestDB_readWriteTimeout(t *testing.T) {
+	db, err := New(postgresDataSource, WithQueryTimeout(5*time.Second),
+		WithReadTimeout(200*time.Millisecond), WithWriteTimeout(time.Second))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("read", func(t *testing.T) {
+		var n int
+		assert.NoError(t, db.Get(ctx, &n, "SELECT 1"))
+		assert.ErrorIs(t, db.Get(ctx, &n, "SELECT 1 FROM pg_sleep(0.5)"), context.DeadlineExceeded)
+		var names []string
+		assert.ErrorIs(t, db.GetAll(ctx, &names, "SELECT 'foo' FROM pg_sleep(0.5)"), context.DeadlineExceeded)
+	})
+
+	t.Run("write", func(t *testing.T) {
+		_, err := db.Exec(ctx, "SELECT pg_sleep(0.5)")
+		assert.NoError(t, err)
+		_, err = db.Exec(ctx, "SELECT pg_sleep(2)")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("runInTx", func(t *testing.T) {
+		p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+		assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			_, err := tx.Exec("SELECT pg_sleep(0.5)")
+			if err != nil {
+				return err
+			}
+			return tx.Insert(p1)
+		}))
+
+		p2 := &personModel{Name: "Jolly Jumper", Email: NullString("jolly@example.com")}
+		assert.Error(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			if err := tx.Insert(p2); err != nil {
+				return err
+			}
+			_, err := tx.Exec("SELECT pg_sleep(0.6)")
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec("SELECT pg_sleep(0.6)")
+			return err
+		}))
+
+		var p personModel
+		assert.NoError(t, db.Select(ctx, &p, p1.GetID()))
+		assert.ErrorIs(t, db.Select(ctx, &p, p2.GetID()), sql.ErrNoRows)
+	})
+}
+
+func TestDB_Reload(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{
+		Name:  "Lucky Luke",
+		Email: NullString("lucky@example.com"),
+	}
+	require.NoError(t, db.Insert(ctx, p1))
+
+	t.Run("ok", func(t *testing.T) {
+		_, err := db.Exec(ctx, "UPDATE person_test SET name = 'Averell Dalton' WHERE id = $1", p1.GetID())
+		require.NoError(t, err)
+
+		p := &personModel{
+			Base:  Base{ID: p1.GetID()},
+			Name:  "Old Name",
+			Email: NullString("old@example.com"),
+		}
+		assert.NoError(t, db.Reload(ctx, p))
+		p1.Name = "Averell Dalton"
+		assertEqualPerson(t, p1, p)
+	})
+
+	t.Run("ok embedded", func(t *testing.T) {
+		p := &personModelBinded{
+			personModel: personModel{Base: Base{ID: p1.GetID()}},
+		}
+		dbr, err := New(postgresDataSource, WithRebindModel())
+		require.NoError(t, err)
+		defer dbr.Close()
+		assert.NoError(t, dbr.Reload(ctx, p))
+		assertEqualPerson(t, p1, &p.personModel)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		p := &personModel{
+			Base: Base{ID: "3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1"},
+			Name: "Ghost",
+		}
+		assert.ErrorIs(t, db.Reload(ctx, p), sql.ErrNoRows)
+		assert.Equal(t, "Ghost", p.Name)
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		p := *p1
+		require.NoError(t, db.Delete(ctx, p1))
+		assert.ErrorIs(t, db.Reload(ctx, &p), sql.ErrNoRows)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var p *personModel
+		assert.Error(t, db.Reload(ctx, p))
+	})
+
+	t.Run("exec (clear table)", func(t *testing.T) {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+}
+
+func TestDB_GetStruct(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p2 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2}))
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	type report struct {
+		Total int64     `db:"total"`
+		Last  time.Time `db:"last"`
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		var r report
+		assert.NoError(t, db.GetStruct(ctx, &r, "SELECT count(*) AS total, max(created_at) AS last FROM person_test"))
+		assert.Equal(t, int64(2), r.Total)
+		assert.Equal(t, p2.CreatedAt.Truncate(time.Second), r.Last.UTC().Truncate(time.Second))
+	})
+
+	t.Run("ok projection", func(t *testing.T) {
+		var r struct {
+			Name string `db:"name"`
+		}
+		assert.NoError(t, db.GetStruct(ctx, &r, "SELECT name FROM person_test WHERE id = $1", p1.GetID()))
+		assert.Equal(t, "Lucky Luke", r.Name)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var r report
+		assert.ErrorIs(t, db.GetStruct(ctx, &r, "SELECT count(*) AS total, max(created_at) AS last FROM person_test GROUP BY name HAVING false"), sql.ErrNoRows)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var r report
+		assert.Error(t, db.GetStruct(ctx, r, "SELECT count(*) AS total, max(created_at) AS last FROM person_test"))
+		assert.Error(t, db.GetStruct(ctx, &r, "SELECT count(*) AS total, max(created_at) AS last, 1 AS extra FROM person_test"))
+	})
+
+	t.Run("fail not a struct", func(t *testing.T) {
+		var n int64
+		assert.Error(t, db.GetStruct(ctx, &n, "SELECT count(*) FROM person_test"))
+		var last sql.NullTime
+		assert.Error(t, db.GetStruct(ctx, &last, "SELECT max(created_at) FROM person_test"))
+		assert.Error(t, db.GetStruct(ctx, (*report)(nil), "SELECT count(*) AS total, max(created_at) AS last FROM person_test"))
+		assert.Error(t, db.GetStruct(ctx, nil, "SELECT count(*) AS total, max(created_at) AS last FROM person_test"))
+	})
+}
+
+func TestTx_InsertAll(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+		_, err = db.Exec(ctx, "DELETE FROM types_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		p1 := &personModel{
+			Name:  "Lucky Luke",
+			Email: NullString("lucky@example.com"),
+		}
+		p2 := &personModelExtra{
+			personModel: personModel{
+				Base: Base{
+					ID: "d59a4685-9ab9-4323-9af9-14ca352cc65b",
+				},
+				Name:  "Jolly Jumper",
+				Email: NullString("jolly@example.com"),
+			},
+		}
+		t1 := &typesModel{
+			Email: CIText("Lucky@Example.com"),
+		}
+
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		assert.NoError(t, tx.InsertAll(p1, p2, t1))
+		assert.NoError(t, tx.Commit())
+
+		var p personModel
+		assert.NoError(t, db.Select(ctx, &p, p1.GetID()))
+		assertEqualPerson(t, p1, &p)
+		assert.NoError(t, db.Select(ctx, &p, p2.GetID()))
+		assertEqualPerson(t, &p2.personModel, &p)
+		var tm typesModel
+		assert.NoError(t, db.Select(ctx, &tm, t1.GetID()))
+		assertEqualTypes(t, t1, &tm)
+	})
+
+	t.Run("ok empty", func(t *testing.T) {
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		assert.NoError(t, tx.InsertAll())
+		assert.NoError(t, tx.Rollback())
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		p1 := &personModel{
+			Name:  "Joe Dalton",
+			Email: NullString("joe@example.com"),
+		}
+		// Duplicated email
+		p2 := &personModel{
+			Name:  "Lucky Luke",
+			Email: NullString("lucky@example.com"),
+		}
+		p3 := &personModel{
+			Name:  "Averell Dalton",
+			Email: NullString("averell@example.com"),
+		}
+
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		err = tx.InsertAll(p1, p2, p3)
+		assert.True(t, IsUniqueViolation(err))
+		assert.NotEmpty(t, p1.GetID())
+		assert.Empty(t, p3.GetID())
+		assert.NoError(t, tx.Rollback())
+
+		var p personModel
+		assert.ErrorIs(t, db.Select(ctx, &p, p1.GetID()), sql.ErrNoRows)
+	})
+}
+
+type personModelValidated struct {
+	personModel
+}
+
+func (m *personModelValidated) Validate() error {
+	if m.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	return nil
+}
+
+func TestDB_validate(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModelValidated{personModel{
+		Name:  "Lucky Luke",
+		Email: NullString("lucky@example.com"),
+	}}
+
+	t.Run("insert", func(t *testing.T) {
+		assert.NoError(t, db.Insert(ctx, p1))
+		assert.NotEmpty(t, p1.GetID())
+
+		p := &personModelValidated{personModel{Email: NullString("joe@example.com")}}
+		assert.EqualError(t, db.Insert(ctx, p), "name cannot be empty")
+		assert.Empty(t, p.GetID())
+		assert.True(t, p.CreatedAt.IsZero())
+	})
+
+	t.Run("insertBatch", func(t *testing.T) {
+		p2 := &personModelValidated{personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}}
+		p3 := &personModelValidated{personModel{Email: NullString("jack@example.com")}}
+		assert.EqualError(t, db.InsertBatch(ctx, []Model{p2, p3}), "name cannot be empty")
+		assert.Empty(t, p2.GetID())
+	})
+
+	t.Run("update", func(t *testing.T) {
+		p := *p1
+		p.Name = ""
+		assert.EqualError(t, db.Update(ctx, &p), "name cannot be empty")
+
+		var got personModel
+		require.NoError(t, db.Select(ctx, &got, p1.GetID()))
+		assertEqualPerson(t, &p1.personModel, &got)
+	})
+
+	t.Run("tx", func(t *testing.T) {
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		defer func() {
+			assert.NoError(t, tx.Rollback())
+		}()
+
+		p := &personModelValidated{personModel{Email: NullString("joe@example.com")}}
+		assert.EqualError(t, tx.Insert(p), "name cannot be empty")
+		assert.EqualError(t, tx.InsertAll(p), "name cannot be empty")
+		assert.Empty(t, p.GetID())
+
+		pp := *p1
+		pp.Name = ""
+		assert.EqualError(t, tx.Update(&pp), "name cannot be empty")
+	})
+}
+
+func TestAffectedRows(t *testing.T) {
+	tests := []struct {
+		name      string
+		res       sql.Result
+		want      int64
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok", driver.RowsAffected(3), 3, assert.NoError},
+		{"ok zero", driver.RowsAffected(0), 0, assert.NoError},
+		{"fail", driver.ResultNoRows, 0, assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AffectedRows(tt.res)
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDB_statementCache(t *testing.T) {
+	db, err := New(postgresDataSource, WithStatementCache(2), WithMaxOpenConnections(1))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		for _, q := range []string{"SELECT $1::int", "SELECT $1::int + 1", "SELECT $1::int + 2"} {
+			var n int
+			assert.NoError(t, db.Get(ctx, &n, q, i))
+		}
+	}
+
+	assert.NoError(t, db.RawConn(ctx, func(conn *pgx.Conn) error {
+		assert.Equal(t, pgx.QueryExecModeCacheStatement, conn.Config().DefaultQueryExecMode)
+		assert.Equal(t, 2, conn.Config().StatementCacheCapacity)
+
+		// The evicted statements are deallocated, so the connection has at
+		// most 2 prepared statements.
+		var n int
+		require.NoError(t, conn.QueryRow(ctx, "SELECT count(*) FROM pg_prepared_statements", pgx.QueryExecModeSimpleProtocol).Scan(&n))
+		assert.Positive(t, n)
+		assert.LessOrEqual(t, n, 2)
+		return nil
+	}))
+
+	// By default, pgx keeps up to 512 statements.
+	db, err = New(postgresDataSource)
+	require.NoError(t, err)
+	defer db.Close()
+	assert.NoError(t, db.RawConn(ctx, func(conn *pgx.Conn) error {
+		assert.Equal(t, 512, conn.Config().StatementCacheCapacity)
+		return nil
+	}))
+
+	sqlDB, err := sql.Open("pgx/v5", postgresDataSource)
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	_, err = NewDB(sqlDB, "pgx/v5", WithStatementCache(2))
+	assert.Error(t, err)
+}
+
+func TestDB_tlsConfig(t *testing.T) {
+	sqlDB, err := sql.Open("pgx/v5", postgresDataSource)
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	_, err = NewDB(sqlDB, "pgx/v5", WithTLSConfig(&tls.Config{}))
+	assert.Error(t, err)
+
+	// The test server does not support TLS, the connection must not fall back
+	// to plaintext even with sslmode=disable.
+	_, err = New(postgresDataSource, WithTLSConfig(&tls.Config{InsecureSkipVerify: true}))
+	assert.Error(t, err)
+}
+
+func Test_applyTLSConfig(t *testing.T) {
+	type hostTLS struct {
+		Host       string
+		ServerName string
+	}
+	tests := []struct {
+		name      string
+		dsn       string
+		tlsConfig *tls.Config
+		want      []hostTLS
+	}{
+		{"ok", "postgres://user@db1/test?sslmode=disable", &tls.Config{}, []hostTLS{{"db1", "db1"}}},
+		{"ok prefer", "postgres://user@db1/test?sslmode=prefer", &tls.Config{}, []hostTLS{{"db1", "db1"}}},
+		{"ok multi-host prefer", "postgres://user@db1,db2:5433/test?sslmode=prefer", &tls.Config{}, []hostTLS{{"db1", "db1"}, {"db2", "db2"}}},
+		{"ok multi-host disable", "postgres://user@db1,db2/test?sslmode=disable", &tls.Config{}, []hostTLS{{"db1", "db1"}, {"db2", "db2"}}},
+		{"ok server name", "host=db1,db2 user=user sslmode=prefer", &tls.Config{ServerName: "db.example.com"}, []hostTLS{{"db1", "db.example.com"}, {"db2", "db.example.com"}}},
+		{"ok insecure", "host=db1,db2 user=user sslmode=prefer", &tls.Config{InsecureSkipVerify: true}, []hostTLS{{"db1", ""}, {"db2", ""}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := pgx.ParseConfig(tt.dsn)
+			require.NoError(t, err)
+			applyTLSConfig(config, tt.tlsConfig)
+
+			require.NotNil(t, config.TLSConfig)
+			got := []hostTLS{{config.Host, config.TLSConfig.ServerName}}
+			for _, fb := range config.Fallbacks {
+				require.NotNil(t, fb.TLSConfig)
+				got = append(got, hostTLS{fb.Host, fb.TLSConfig.ServerName})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDB_credentialProvider(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	password := dbPassword
+	provider := func(ctx context.Context) (string, string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if password == "" {
+			return "", "", errors.New("no credentials")
+		}
+		return dbUser, password, nil
+	}
+	setPassword := func(s string) {
+		mu.Lock()
+		password = s
+		mu.Unlock()
+	}
+
+	// The data source name does not contain valid credentials
+	dataSource := strings.ReplaceAll(postgresDataSource, dbUser+":"+dbPassword, "foo:bar")
+	db, err := New(dataSource, WithCredentialProvider(provider))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	var user string
+	assert.NoError(t, db.Get(ctx, &user, "SELECT current_user"))
+	assert.Equal(t, dbUser, user)
+	mu.Lock()
+	assert.Positive(t, calls)
+	mu.Unlock()
+
+	// Existing connections are still valid
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	setPassword("wrong-password")
+	assert.NoError(t, conn.Get(ctx, &user, "SELECT current_user"))
+	assert.NoError(t, conn.Close())
+
+	// New connections use the new credentials
+	db.DB().SetMaxIdleConns(0)
+	assert.Error(t, db.Get(ctx, &user, "SELECT current_user"))
+	setPassword("")
+	assert.Error(t, db.Get(ctx, &user, "SELECT current_user"))
+	setPassword(dbPassword)
+	assert.NoError(t, db.Get(ctx, &user, "SELECT current_user"))
+
+	sqlDB, err := sql.Open("pgx/v5", postgresDataSource)
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	_, err = NewDB(sqlDB, "pgx/v5", WithCredentialProvider(provider))
+	assert.Error(t, err)
+	_, err = New(postgresDataSource, WithDriver("postgres"), WithCredentialProvider(provider))
+	assert.Error(t, err)
+}
+
+func TestDB_cancelRequest(t *testing.T) {
+	db, err := New(postgresDataSource, WithCancelRequest(5*time.Second))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	running := func() int {
+		var n int
+		require.NoError(t, db.Get(ctx, &n, "SELECT count(*) FROM pg_stat_activity WHERE state = 'active' AND query = 'SELECT pg_sleep(30)'"))
+		return n
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	_, err = db.Exec(cctx, "SELECT pg_sleep(30)")
+	assert.Error(t, err)
+	// The query is canceled on the server and the connection can be reused.
+	assert.Equal(t, 0, running())
+	var n int
+	assert.NoError(t, db.Get(ctx, &n, "SELECT 1"))
+
+	sqlDB, err := sql.Open("pgx/v5", postgresDataSource)
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	_, err = NewDB(sqlDB, "pgx/v5", WithCancelRequest(time.Second))
+	assert.Error(t, err)
+}
+
+func TestDB_statementTimeout(t *testing.T) {
+	db, err := New(postgresDataSource, WithStatementTimeout(1500*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	var s string
+	assert.NoError(t, db.Get(ctx, &s, "SHOW statement_timeout"))
+	assert.Equal(t, "1500ms", s)
+
+	_, err = db.Exec(ctx, "SELECT pg_sleep(2)")
+	var pgErr *pgconn.PgError
+	if assert.ErrorAs(t, err, &pgErr) {
+		assert.Equal(t, "57014", pgErr.Code)
+	}
+
+	sqlDB, err := sql.Open("pgx/v5", postgresDataSource)
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	_, err = NewDB(sqlDB, "pgx/v5", WithStatementTimeout(time.Second))
+	assert.Error(t, err)
+}
+
+func TestDB_InsertAudited(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	db, err := New(postgresDataSource, WithClock(clock.NewMock(now)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		p := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+		query, args, err := db.InsertAudited(ctx, p)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, p.GetID())
+		assert.Equal(t, "INSERT INTO person_test (created_at, updated_at, deleted_at, name, email) VALUES ($1, $2, $3, $4, $5) RETURNING id", query)
+		assert.Equal(t, []any{now, now, sql.NullTime{}, "Lucky Luke", NullString("lucky@example.com")}, args)
+	})
+
+	t.Run("ok exec insert", func(t *testing.T) {
+		p := &personModelExtra{personModel{
+			Base:  Base{ID: "d59a4685-9ab9-4323-9af9-14ca352cc65b"},
+			Name:  "Jolly Jumper",
+			Email: NullString("jolly@example.com"),
+		}}
+		query, args, err := db.InsertAudited(ctx, p)
+		assert.NoError(t, err)
+		assert.Equal(t, "INSERT INTO person_test (id, created_at, updated_at, deleted_at, name, email) VALUES ($1, $2, $3, $4, $5, $6)", query)
+		assert.Equal(t, []any{"d59a4685-9ab9-4323-9af9-14ca352cc65b", now, now, sql.NullTime{}, "Jolly Jumper", NullString("jolly@example.com")}, args)
+	})
+
+	t.Run("ok tx", func(t *testing.T) {
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		p := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+		query, args, err := tx.InsertAudited(p)
+		assert.NoError(t, err)
+		assert.Equal(t, "INSERT INTO person_test (created_at, updated_at, deleted_at, name, email) VALUES ($1, $2, $3, $4, $5) RETURNING id", query)
+		assert.Equal(t, []any{now, now, sql.NullTime{}, "Joe Dalton", NullString("joe@example.com")}, args)
+		assert.NoError(t, tx.Commit())
+
+		var got personModel
+		assert.NoError(t, db.Select(ctx, &got, p.GetID()))
+		assertEqualPerson(t, p, &got)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		query, args, err := db.InsertAudited(ctx, &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")})
+		assert.True(t, IsUniqueViolation(err))
+		assert.Empty(t, query)
+		assert.Nil(t, args)
+
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		query, args, err = tx.InsertAudited(&personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")})
+		assert.True(t, IsUniqueViolation(err))
+		assert.Empty(t, query)
+		assert.Nil(t, args)
+	})
+}
+
+// personModelCancel cancels a context when its insert query is built.
+type personModelCancel struct {
+	personModel
+	cancel context.CancelFunc
+}
+
+func (m *personModelCancel) Insert() string {
+	m.cancel()
+	return personInsertQ
+}
+
+func TestDB_InsertBatch_cancel(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p1 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p2 := &personModelCancel{
+		personModel: personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")},
+		cancel:      cancel,
+	}
+	p3 := &personModel{Name: "William Dalton", Email: NullString("william@example.com")}
+
+	assert.ErrorIs(t, db.InsertBatch(ctx, []Model{p1, p2, p3}), context.Canceled)
+	assert.NotEmpty(t, p1.GetID())
+	assert.Empty(t, p2.GetID())
+	assert.Empty(t, p3.GetID())
+
+	var n int
+	assert.NoError(t, db.Get(context.Background(), &n, "SELECT count(*) FROM person_test"))
+	assert.Equal(t, 0, n)
+}
+
+func TestDB_InsertDefaults(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM types_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		m1, m2 := new(typesModel), new(typesModel)
+		assert.NoError(t, db.InsertDefaults(ctx, m1))
+		assert.NoError(t, db.InsertDefaults(ctx, m2))
+		assert.NotEmpty(t, m1.GetID())
+		assert.NotEqual(t, m1.GetID(), m2.GetID())
+		assert.False(t, m1.CreatedAt.IsZero())
+		assert.Empty(t, m1.Email)
+
+		var got typesModel
+		assert.NoError(t, db.Select(ctx, &got, m1.GetID()))
+		assertEqualTypes(t, m1, &got)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		// name and email are NOT NULL without a default
+		assert.Error(t, db.InsertDefaults(ctx, new(personModel)))
+
+		dbr, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer dbr.Close()
+		assert.ErrorIs(t, dbr.InsertDefaults(ctx, new(typesModel)), ErrReadOnly)
+	})
+}
+
+func TestDB_InsertBatchIndexed(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+		p2 := &personModelExtra{personModel: personModel{
+			Base: Base{ID: "61c1e3cf-a1a1-4c4b-9e8b-8a2f0b4b7c1d"}, Name: "Jolly Jumper", Email: NullString("jolly@example.com"),
+		}}
+		p3 := &personModel{Name: "Rantanplan", Email: NullString("rantanplan@example.com")}
+
+		got, err := db.InsertBatchIndexed(ctx, []Model{p1, p2, p3})
+		require.NoError(t, err)
+		assert.Len(t, got, 3)
+		assert.Same(t, p1, got[p1.GetID()])
+		assert.Same(t, p2, got["61c1e3cf-a1a1-4c4b-9e8b-8a2f0b4b7c1d"])
+		assert.Same(t, p3, got[p3.GetID()])
+	})
+
+	t.Run("ok empty", func(t *testing.T) {
+		got, err := db.InsertBatchIndexed(ctx, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		got, err := db.InsertBatchIndexed(ctx, []Model{
+			&personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")},
+		})
+		assert.True(t, IsUniqueViolation(err))
+		assert.Nil(t, got)
+	})
+}
+
+func TestDB_UpdateMap(t *testing.T) {
+	t0 := time.Now().UTC().Truncate(time.Second)
+	db, err := New(postgresDataSource, WithClock(clock.NewMock(t0)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.Insert(ctx, p1))
+
+	t.Run("ok", func(t *testing.T) {
+		t1 := t0.Add(time.Hour)
+		dbc, err := New(postgresDataSource, WithClock(clock.NewMock(t1)))
+		require.NoError(t, err)
+		defer dbc.Close()
+
+		p := &personModel{Base: Base{ID: p1.GetID()}}
+		assert.NoError(t, dbc.UpdateMap(ctx, p, map[string]any{
+			"name":  "Averell Dalton",
+			"email": "averell@example.com",
+		}))
+		assert.Equal(t, t1, p.UpdatedAt)
+		assert.Empty(t, p.Name)
+
+		assert.NoError(t, db.Reload(ctx, p))
+		assert.Equal(t, "Averell Dalton", p.Name)
+		assert.Equal(t, NullString("averell@example.com"), p.Email)
+		assert.Equal(t, t0, p.CreatedAt.UTC())
+		assert.Equal(t, t1, p.UpdatedAt.UTC())
+	})
+
+	t.Run("ok only updated_at", func(t *testing.T) {
+		p := &personModel{Base: Base{ID: p1.GetID()}}
+		assert.NoError(t, db.UpdateMap(ctx, p, nil))
+		assert.NoError(t, db.Reload(ctx, p))
+		assert.Equal(t, t0, p.UpdatedAt.UTC())
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		p := &personModel{Base: Base{ID: p1.GetID()}}
+		assert.Error(t, db.UpdateMap(ctx, p, map[string]any{"phone": "555-1234"}))
+		assert.Error(t, db.UpdateMap(ctx, p, map[string]any{"name = 'x', email": "foo"}))
+		assert.Error(t, db.UpdateMap(ctx, p, map[string]any{"id": "3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1"}))
+		assert.Error(t, db.UpdateMap(ctx, p, map[string]any{"updated_at": time.Now()}))
+
+		p2 := &personModel{Name: "Jolly Jumper", Email: NullString("jolly@example.com")}
+		require.NoError(t, db.Insert(ctx, p2))
+		assert.True(t, IsUniqueViolation(db.UpdateMap(ctx, p, map[string]any{"email": "jolly@example.com"})))
+
+		missing := &personModel{Base: Base{ID: "3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1"}}
+		assert.ErrorIs(t, db.UpdateMap(ctx, missing, map[string]any{"name": "Ghost"}), sql.ErrNoRows)
+
+		dbr, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer dbr.Close()
+		assert.ErrorIs(t, dbr.UpdateMap(ctx, p, map[string]any{"name": "Joe Dalton"}), ErrReadOnly)
+	})
+}
+
+type personModelCustomUpdate struct {
+	personModel
+}
+
+func (m *personModelCustomUpdate) Update() string {
+	return "UPDATE person_test SET name = :name WHERE id = :id"
+}
+
+type timestampsModel struct {
+	ID        string    `db:"id" dbtable:"timestamps_test"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (m *timestampsModel) GetID() string            { return m.ID }
+func (m *timestampsModel) SetID(id string)          { m.ID = id }
+func (m *timestampsModel) SetCreatedAt(t time.Time) { m.CreatedAt = t }
+func (m *timestampsModel) SetUpdatedAt(t time.Time) { m.UpdatedAt = t }
+func (m *timestampsModel) SetDeletedAt(time.Time)   {}
+func (m *timestampsModel) Select() string           { return "" }
+func (m *timestampsModel) Insert() string           { return "" }
+func (m *timestampsModel) Delete() string           { return "" }
+func (m *timestampsModel) Update() string {
+	return "UPDATE timestamps_test SET updated_at = :updated_at WHERE id = :id"
+}
+
+func Test_updateIfChangedQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		arg       Model
+		want      string
+		assertion assert.ErrorAssertionFunc
+	}{
+		{"ok", &personModel{}, "UPDATE person_test SET deleted_at = :deleted_at, name = :name, email = :email, updated_at = :updated_at WHERE id = :id AND (deleted_at, name, email) IS DISTINCT FROM (:deleted_at, :name, :email)", assert.NoError},
+		{"ok generated", &generatedModel{}, "UPDATE generated_test SET deleted_at = :deleted_at, sku = :sku, price_cents = :price_cents, quantity = :quantity, updated_at = :updated_at WHERE id = :id AND (deleted_at, sku, price_cents, quantity) IS DISTINCT FROM (:deleted_at, :sku, :price_cents, :quantity)", assert.NoError},
+		{"fail custom update", &personModelCustomUpdate{}, "", assert.Error},
+		{"fail no columns", &timestampsModel{}, "", assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := queryBuilder(tt.arg)
+			require.NoError(t, err)
+			got, err := updateIfChangedQuery(b, tt.arg)
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDB_UpdateIfChanged(t *testing.T) {
+	t0 := time.Now().UTC().Truncate(time.Second)
+	db, err := New(postgresDataSource, WithClock(clock.NewMock(t0)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.Insert(ctx, p1))
+
+	t1 := t0.Add(time.Hour)
+	dbc, err := New(postgresDataSource, WithClock(clock.NewMock(t1)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, dbc.Close())
+	})
+
+	t.Run("ok unchanged", func(t *testing.T) {
+		p := &personModel{Base: Base{ID: p1.GetID(), CreatedAt: t0}, Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+		updated, err := dbc.UpdateIfChanged(ctx, p)
+		assert.NoError(t, err)
+		assert.False(t, updated)
+		assert.Equal(t, t0, p.UpdatedAt.UTC())
+
+		assert.NoError(t, db.Reload(ctx, p))
+		assert.Equal(t, t0, p.UpdatedAt.UTC())
+	})
+
+	t.Run("ok changed", func(t *testing.T) {
+		p := &personModel{Base: Base{ID: p1.GetID(), CreatedAt: t0}, Name: "Lucky Luke", Email: NullString("luke@example.com")}
+		updated, err := dbc.UpdateIfChanged(ctx, p)
+		assert.NoError(t, err)
+		assert.True(t, updated)
+		assert.Equal(t, t1, p.UpdatedAt)
+
+		assert.NoError(t, db.Reload(ctx, p))
+		assert.Equal(t, NullString("luke@example.com"), p.Email)
+		assert.Equal(t, t1, p.UpdatedAt.UTC())
+	})
+
+	t.Run("ok changed to null", func(t *testing.T) {
+		p := &personModel{Base: Base{ID: p1.GetID(), CreatedAt: t0}, Name: "Lucky Luke"}
+		updated, err := db.UpdateIfChanged(ctx, p)
+		assert.NoError(t, err)
+		assert.True(t, updated)
+
+		updated, err = db.UpdateIfChanged(ctx, p)
+		assert.NoError(t, err)
+		assert.False(t, updated)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		missing := &personModel{Base: Base{ID: "3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1"}, Name: "Ghost"}
+		_, err := db.UpdateIfChanged(ctx, missing)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		p2 := &personModel{Name: "Jolly Jumper", Email: NullString("jolly@example.com")}
+		p3 := &personModel{Name: "Rantanplan", Email: NullString("rantanplan@example.com")}
+		require.NoError(t, db.InsertBatch(ctx, []Model{p2, p3}))
+		p2.Email = p3.Email
+		_, err = db.UpdateIfChanged(ctx, p2)
+		assert.True(t, IsUniqueViolation(err))
+
+		updated, err := db.UpdateIfChanged(ctx, &timestampsModel{ID: p1.GetID()})
+		assert.Error(t, err)
+		assert.False(t, updated)
+
+		dbr, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer dbr.Close()
+		_, err = dbr.UpdateIfChanged(ctx, p1)
+		assert.ErrorIs(t, err, ErrReadOnly)
+	})
+}
+
+func TestDB_UpdateWhereReturning(t *testing.T) {
+	t0 := time.Now().UTC().Truncate(time.Second)
+	db, err := New(postgresDataSource, WithClock(clock.NewMock(t0)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p2 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+	p3 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p4 := &personModel{Name: "Averell Dalton", Email: NullString("averell@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2, p3, p4}))
+	require.NoError(t, db.Delete(ctx, p4))
+
+	t.Run("ok", func(t *testing.T) {
+		t1 := t0.Add(time.Hour)
+		dbc, err := New(postgresDataSource, WithClock(clock.NewMock(t1)))
+		require.NoError(t, err)
+		defer dbc.Close()
+
+		var got []*personModel
+		assert.NoError(t, dbc.UpdateWhereReturning(ctx, &got, &personModel{},
+			map[string]any{"name": "A Dalton"}, "name LIKE ? OR email = ?", "% Dalton", "nobody@example.com"))
+		require.Len(t, got, 2)
+		ids := []string{got[0].GetID(), got[1].GetID()}
+		assert.ElementsMatch(t, []string{p1.GetID(), p2.GetID()}, ids)
+		for _, p := range got {
+			assert.Equal(t, "A Dalton", p.Name)
+			assert.Equal(t, t1, p.UpdatedAt.UTC())
+		}
+
+		var p personModel
+		assert.NoError(t, db.Select(ctx, &p, p3.GetID()))
+		assert.Equal(t, "Lucky Luke", p.Name)
+		assert.NoError(t, db.Get(ctx, &p, "SELECT * FROM person_test WHERE id = $1", p4.GetID()))
+		assert.Equal(t, "Averell Dalton", p.Name)
+	})
+
+	t.Run("ok no rows", func(t *testing.T) {
+		var got []*personModel
+		assert.NoError(t, db.UpdateWhereReturning(ctx, &got, &personModel{},
+			map[string]any{"name": "Nobody"}, "name = ?", "Rantanplan"))
+		assert.Empty(t, got)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var got []*personModel
+		assert.Error(t, db.UpdateWhereReturning(ctx, &got, &personModel{}, map[string]any{"phone": "555"}, "true"))
+		assert.Error(t, db.UpdateWhereReturning(ctx, &got, &personModel{}, map[string]any{"id": "foo"}, "true"))
+		assert.Error(t, db.UpdateWhereReturning(ctx, &got, &personModel{}, map[string]any{"name": "foo"}, "missing = ?", 1))
+		assert.True(t, IsUniqueViolation(db.UpdateWhereReturning(ctx, &got, &personModel{}, map[string]any{"email": "same@example.com"}, "true")))
+
+		dbr, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer dbr.Close()
+		assert.ErrorIs(t, dbr.UpdateWhereReturning(ctx, &got, &personModel{}, map[string]any{"name": "foo"}, "true"), ErrReadOnly)
+	})
+}
+
+func TestDB_DeleteReturning(t *testing.T) {
+	t0 := time.Now().UTC().Truncate(time.Second)
+	db, err := New(postgresDataSource, WithClock(clock.NewMock(t0)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.Insert(ctx, p1))
+	_, err = db.Exec(ctx, "UPDATE person_test SET name = 'Averell Dalton' WHERE id = $1", p1.GetID())
+	require.NoError(t, err)
+
+	t.Run("ok", func(t *testing.T) {
+		p := &personModel{Base: Base{ID: p1.GetID()}}
+		assert.NoError(t, db.DeleteReturning(ctx, p))
+		assert.Equal(t, "Averell Dalton", p.Name)
+		assert.Equal(t, NullString("lucky@example.com"), p.Email)
+		assert.True(t, p.DeletedAt.Valid)
+		assert.Equal(t, t0, p.DeletedAt.Time.UTC())
+
+		var got personModel
+		assert.ErrorIs(t, db.Select(ctx, &got, p1.GetID()), sql.ErrNoRows)
+	})
+
+	t.Run("ok rebind", func(t *testing.T) {
+		dbr, err := New(postgresDataSource, WithRebindModel())
+		require.NoError(t, err)
+		defer dbr.Close()
+
+		p2 := &personModelBinded{personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}}
+		require.NoError(t, dbr.Insert(ctx, p2))
+		p := &personModelBinded{personModel{Base: Base{ID: p2.GetID()}}}
+		assert.NoError(t, dbr.DeleteReturning(ctx, p))
+		assert.Equal(t, "Joe Dalton", p.Name)
+		assert.True(t, p.DeletedAt.Valid)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		p := &personModel{Base: Base{ID: "3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1"}}
+		assert.ErrorIs(t, db.DeleteReturning(ctx, p), sql.ErrNoRows)
+
+		rdb, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer rdb.Close()
+		assert.ErrorIs(t, rdb.DeleteReturning(ctx, p), ErrReadOnly)
+	})
+}
+
+func TestDB_DeleteByIDs(t *testing.T) {
+	t0 := time.Now().UTC().Truncate(time.Second)
+	db, err := New(postgresDataSource, WithClock(clock.NewMock(t0)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p2 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+	p3 := &personModel{Name: "William Dalton", Email: NullString("william@example.com")}
+	p4 := &personModel{Name: "Averell Dalton", Email: NullString("averell@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2, p3, p4}))
+	missingID := "3c4e2a3e-6b0f-4e55-8a0c-0f4f5fb1a6d1"
+
+	t.Run("soft", func(t *testing.T) {
+		n, err := db.DeleteByIDs(ctx, &personModel{}, []string{p1.GetID(), p2.GetID(), missingID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		var got personModel
+		assert.ErrorIs(t, db.Select(ctx, &got, p1.GetID()), sql.ErrNoRows)
+		assert.ErrorIs(t, db.Select(ctx, &got, p2.GetID()), sql.ErrNoRows)
+		assert.NoError(t, db.Select(ctx, &got, p3.GetID()))
+
+		var deletedAt time.Time
+		assert.NoError(t, db.Get(ctx, &deletedAt, "SELECT deleted_at FROM person_test WHERE id = $1", p1.GetID()))
+		assert.Equal(t, t0, deletedAt.UTC())
+
+		// Soft-deleted rows are not counted again
+		n, err = db.DeleteByIDs(ctx, &personModel{}, []string{p1.GetID(), p3.GetID()})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("hard", func(t *testing.T) {
+		n, err := db.HardDeleteByIDs(ctx, &personModel{}, []string{p1.GetID(), p4.GetID(), missingID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		var count int
+		assert.NoError(t, db.Get(ctx, &count, "SELECT count(*) FROM person_test"))
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		rdb, err := New(postgresDataSource)
+		require.NoError(t, err)
+		require.NoError(t, rdb.Close())
+
+		// The closed database is not used
+		n, err := rdb.DeleteByIDs(ctx, &personModel{}, nil)
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+		n, err = rdb.HardDeleteByIDs(ctx, &personModel{}, []string{})
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		_, err := db.DeleteByIDs(ctx, &personModel{}, []string{"not-a-uuid"})
+		assert.Error(t, err)
+		_, err = db.HardDeleteByIDs(ctx, &personModel{}, []string{"not-a-uuid"})
+		assert.Error(t, err)
+
+		rdb, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer rdb.Close()
+		_, err = rdb.DeleteByIDs(ctx, &personModel{}, []string{p3.GetID()})
+		assert.ErrorIs(t, err, ErrReadOnly)
+		_, err = rdb.HardDeleteByIDs(ctx, &personModel{}, []string{p3.GetID()})
+		assert.ErrorIs(t, err, ErrReadOnly)
+	})
+}
+
+func TestDB_idCollisionRetry(t *testing.T) {
+	ids := []string{
+		"f2026a37-1334-409a-939b-6a6f5c270724",
+		"ce5b82e5-16cc-45c3-8d82-6678680ee37f",
+	}
+	var generated int
+	newID := func() string {
+		generated++
+		return ids[generated%len(ids)]
+	}
+
+	db, err := New(postgresDataSource, WithIDCollisionRetry(2, newID))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModelExtra{personModel{Base: Base{ID: ids[0]}, Name: "Joe Dalton", Email: NullString("joe@example.com")}}
+	require.NoError(t, db.Insert(ctx, p1))
+
+	t.Run("ok", func(t *testing.T) {
+		p := &personModelExtra{personModel{Base: Base{ID: ids[0]}, Name: "Jack Dalton", Email: NullString("jack@example.com")}}
+		assert.NoError(t, db.Insert(ctx, p))
+		assert.Equal(t, ids[1], p.GetID())
+		assert.Equal(t, 1, generated)
+
+		var got personModel
+		assert.NoError(t, db.Select(ctx, &got, ids[1]))
+		assert.Equal(t, "Jack Dalton", got.Name)
+	})
+
+	t.Run("fail retries", func(t *testing.T) {
+		generated = 0
+		// Both ids exist, newID alternates between them
+		p := &personModelExtra{personModel{Base: Base{ID: ids[0]}, Name: "William Dalton", Email: NullString("william@example.com")}}
+		err := db.Insert(ctx, p)
+		assert.True(t, IsUniqueViolation(err))
+		assert.Equal(t, 2, generated)
+	})
+
+	t.Run("fail other unique violation", func(t *testing.T) {
+		generated = 0
+		p := &personModelExtra{personModel{Base: Base{ID: ids[0]}, Name: "Joe Dalton", Email: NullString("joe@example.com")}}
+		_, err := db.HardDeleteByIDs(ctx, &personModel{}, []string{ids[1]})
+		require.NoError(t, err)
+		p.ID = ids[1]
+		err = db.Insert(ctx, p)
+		assert.True(t, IsUniqueViolation(err))
+		assert.Zero(t, generated)
+	})
+
+	t.Run("fail in transaction", func(t *testing.T) {
+		generated = 0
+		p := &personModelExtra{personModel{Base: Base{ID: ids[0]}, Name: "Averell Dalton", Email: NullString("averell@example.com")}}
+		err := db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			return db.Insert(ctx, p)
+		})
+		assert.True(t, IsUniqueViolation(err))
+		assert.Zero(t, generated)
+	})
+}
+
+func TestDB_ExecReturning(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	p2 := &personModel{Name: "Rantanplan", Email: NullString("rantanplan@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2}))
+	n := &noteModel{PersonID: p1.GetID(), Body: "Guards the Daltons"}
+	require.NoError(t, db.Insert(ctx, n))
+
+	t.Run("ok writable cte", func(t *testing.T) {
+		// Move the note to another person
+		var notes []noteModel
+		assert.NoError(t, db.ExecReturning(ctx, &notes, `WITH deleted AS (
+			DELETE FROM note_test WHERE id = $1 RETURNING id, created_at, updated_at, deleted_at, person_id, body
+		), inserted AS (
+			INSERT INTO note_test (person_id, body) SELECT $2, body FROM deleted RETURNING id, created_at, updated_at, deleted_at, person_id, body
+		) SELECT * FROM deleted UNION ALL SELECT * FROM inserted`, n.GetID(), p2.GetID()))
+		if assert.Len(t, notes, 2) {
+			assert.Equal(t, n.GetID(), notes[0].GetID())
+			assert.Equal(t, p1.GetID(), notes[0].PersonID)
+			assert.NotEqual(t, n.GetID(), notes[1].GetID())
+			assert.Equal(t, p2.GetID(), notes[1].PersonID)
+			assert.Equal(t, "Guards the Daltons", notes[1].Body)
+		}
+	})
+
+	t.Run("ok no rows", func(t *testing.T) {
+		var people []*personModel
+		assert.NoError(t, db.ExecReturning(ctx, &people, "UPDATE person_test SET name = $1 WHERE name = $2 RETURNING *", "Joe Dalton", "Jack Dalton"))
+		assert.Empty(t, people)
+	})
+
+	t.Run("ok update", func(t *testing.T) {
+		var names []string
+		assert.NoError(t, db.ExecReturning(ctx, &names, "UPDATE person_test SET name = upper(name) RETURNING name"))
+		assert.ElementsMatch(t, []string{"LUCKY LUKE", "RANTANPLAN"}, names)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var names []string
+		assert.Error(t, db.ExecReturning(ctx, &names, "UPDATE missing_table SET name = 'foo' RETURNING name"))
+		assert.Error(t, db.ExecReturning(ctx, names, "UPDATE person_test SET name = name RETURNING name"))
+
+		rdb, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer rdb.Close()
+		assert.ErrorIs(t, rdb.ExecReturning(ctx, &names, "UPDATE person_test SET name = name RETURNING name"), ErrReadOnly)
+	})
+}
+
+func TestDB_ExecExpect(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p2 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+	require.NoError(t, db.InsertBatch(ctx, []Model{p1, p2}))
+
+	t.Run("ok", func(t *testing.T) {
+		assert.NoError(t, db.ExecExpect(ctx, "UPDATE person_test SET name = $1 WHERE id = $2", 1, "Joe", p1.GetID()))
+		assert.NoError(t, db.ExecExpect(ctx, "UPDATE person_test SET name = name", 2))
+	})
+
+	t.Run("ok tx", func(t *testing.T) {
+		assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			return tx.ExecExpect("UPDATE person_test SET name = $1 WHERE id = $2", 1, "Jack", p2.GetID())
+		}))
+		assert.ErrorIs(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			return tx.ExecExpect("UPDATE person_test SET name = name WHERE name = $1", 1, "Averell")
+		}), sql.ErrNoRows)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		assert.ErrorIs(t, db.ExecExpect(ctx, "UPDATE person_test SET name = name WHERE name = $1", 1, "Averell"), sql.ErrNoRows)
+		assert.EqualError(t, db.ExecExpect(ctx, "UPDATE person_test SET name = name", 1), "unexpected number of rows: got 2, want 1")
+		assert.Error(t, db.ExecExpect(ctx, "UPDATE missing_table SET name = name", 1))
+
+		rdb, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer rdb.Close()
+		assert.ErrorIs(t, rdb.ExecExpect(ctx, "UPDATE person_test SET name = name", 2), ErrReadOnly)
+	})
+}
+
+func TestDB_unsafeScan(t *testing.T) {
+	db, err := New(postgresDataSource, WithUnsafeScan())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.Insert(ctx, p1))
+
+	const query = "SELECT *, 'Jolly Jumper' AS horse FROM person_test WHERE id = $1"
+
+	t.Run("ok", func(t *testing.T) {
+		var p personModel
+		assert.NoError(t, db.Get(ctx, &p, query, p1.GetID()))
+		assertEqualPerson(t, p1, &p)
+
+		var people []*personModel
+		assert.NoError(t, db.GetAll(ctx, &people, query, p1.GetID()))
+		assertEqualPersons(t, []*personModel{p1}, people)
+
+		rows, err := QueryInto[personModel](ctx, db, query, p1.GetID())
+		require.NoError(t, err)
+		defer rows.Close()
+		assert.True(t, rows.Next())
+		assert.NoError(t, rows.Err())
+	})
+
+	t.Run("ok tx", func(t *testing.T) {
+		assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			var p personModel
+			if err := tx.Get(&p, query, p1.GetID()); err != nil {
+				return err
+			}
+			var people []*personModel
+			return db.GetAll(ctx, &people, query, p1.GetID())
+		}))
+	})
+
+	t.Run("fail safe", func(t *testing.T) {
+		db, err := New(postgresDataSource)
+		require.NoError(t, err)
+		defer db.Close()
+
+		var p personModel
+		assert.ErrorContains(t, db.Get(ctx, &p, query, p1.GetID()), "missing destination name horse")
+		var people []*personModel
+		assert.ErrorContains(t, db.GetAll(ctx, &people, query, p1.GetID()), "missing destination name horse")
+	})
+}
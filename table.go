@@ -0,0 +1,81 @@
+package sequel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.step.sm/qb"
+)
+
+// tableQueryBuilder returns a query builder for the columns of the given model
+// and the given table.
+func tableQueryBuilder(m Model, table string) (*qb.QueryBuilder, error) {
+	if !isIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	b, err := queryBuilder(m)
+	if err != nil {
+		return nil, err
+	}
+	tb := *b
+	tb.Table = table
+	return &tb, nil
+}
+
+// InsertInto inserts the given model in the given table instead of the one in
+// the model's dbtable tag. The insert query is generated from the db tags of
+// the model, so the table must contain the same columns. It is useful for
+// sharded or partitioned tables, like events_2024 and events_2025, where the
+// table is known at runtime.
+//
+// As with Insert, if the model implements ModelWithExecInsert, the id is
+// inserted and not returned by the database.
+func (d *DB) InsertInto(ctx context.Context, table string, arg Model) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "insert", arg, table, "")
+	b, err := tableQueryBuilder(arg, table)
+	if err != nil {
+		return err
+	}
+	b = writeBuilder(b, generatedColumns(arg))
+	query := b.NamedInsertWithReturning()
+	if _, ok := arg.(ModelWithExecInsert); ok {
+		query = b.NamedInsert()
+	}
+	_, _, err = d.insert(ctx, arg, query)
+	return err
+}
+
+// SelectFrom populates the given model with the result of a select by id query
+// on the given table instead of the one in the model's dbtable tag. The query
+// is generated from the db tags of the model, and soft-deleted rows are not
+// included.
+func (d *DB) SelectFrom(ctx context.Context, table string, dest Model, id string) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "select", dest, table, "")
+	b, err := tableQueryBuilder(dest, table)
+	if err != nil {
+		return err
+	}
+	return d.Get(ctx, dest, d.Rebind(b.Select()), id)
+}
+
+// Truncate empties the given tables with `TRUNCATE ... RESTART IDENTITY
+// CASCADE`, resetting their sequences and also emptying the tables that
+// reference them with foreign keys. It is intended for the setup and teardown
+// of tests, and it fails with ErrReadOnly on a read-only DB.
+func (d *DB) Truncate(ctx context.Context, tables ...string) error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	if len(tables) == 0 {
+		return errors.New("truncate requires at least one table")
+	}
+	for _, t := range tables {
+		if !isIdentifier(t) {
+			return fmt.Errorf("invalid table name %q", t)
+		}
+	}
+	_, err := d.Exec(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" RESTART IDENTITY CASCADE")
+	return err
+}
@@ -0,0 +1,122 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_InsertInto(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test_2024")
+		assert.NoError(t, err)
+	})
+
+	p1 := &personModel{
+		Name:  "Lucky Luke",
+		Email: NullString("lucky@example.com"),
+	}
+	p2 := &personModelExtra{
+		personModel: personModel{
+			Base: Base{
+				ID: "d59a4685-9ab9-4323-9af9-14ca352cc65b",
+			},
+			Name:  "Jolly Jumper",
+			Email: NullString("jolly@example.com"),
+		},
+	}
+
+	t.Run("insert", func(t *testing.T) {
+		assert.NoError(t, db.InsertInto(ctx, "person_test_2024", p1))
+		assert.NotEmpty(t, p1.GetID())
+		assert.NoError(t, db.InsertInto(ctx, "public.person_test_2024", p2))
+	})
+
+	t.Run("select", func(t *testing.T) {
+		var p personModel
+		assert.NoError(t, db.SelectFrom(ctx, "person_test_2024", &p, p1.GetID()))
+		assertEqualPerson(t, p1, &p)
+		assert.NoError(t, db.SelectFrom(ctx, "person_test_2024", &p, p2.GetID()))
+		assertEqualPerson(t, &p2.personModel, &p)
+
+		// The rows are not in the model's table
+		assert.ErrorIs(t, db.Select(ctx, &p, p1.GetID()), sql.ErrNoRows)
+	})
+
+	t.Run("select deleted", func(t *testing.T) {
+		_, err := db.Exec(ctx, "UPDATE person_test_2024 SET deleted_at = NOW() WHERE id = $1", p2.GetID())
+		require.NoError(t, err)
+		var p personModel
+		assert.ErrorIs(t, db.SelectFrom(ctx, "person_test_2024", &p, p2.GetID()), sql.ErrNoRows)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var p personModel
+		assert.Error(t, db.InsertInto(ctx, "person_test_2025", &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}))
+		assert.Error(t, db.InsertInto(ctx, "person_test_2024; DROP TABLE person_test", &personModel{Name: "Joe Dalton"}))
+		assert.Error(t, db.SelectFrom(ctx, "person_test_2024 --", &p, p1.GetID()))
+		assert.Error(t, db.SelectFrom(ctx, "person_test_2025", &p, p1.GetID()))
+	})
+}
+
+func Test_tableQueryBuilder(t *testing.T) {
+	b, err := tableQueryBuilder(&personModel{}, "person_test_2024")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id, created_at, updated_at, deleted_at, name, email FROM person_test_2024 WHERE id = ? AND deleted_at IS NULL", b.Select())
+
+	// The cached query builder is not modified
+	b, err = queryBuilder(&personModel{})
+	require.NoError(t, err)
+	assert.Equal(t, "person_test", b.Table)
+
+	_, err = tableQueryBuilder(&personModel{}, "person_test_2024; --")
+	assert.Error(t, err)
+}
+
+func TestDB_Truncate(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	count := func(t *testing.T, table string) int {
+		t.Helper()
+		var n int
+		require.NoError(t, db.Get(ctx, &n, "SELECT count(*) FROM "+table))
+		return n
+	}
+
+	p := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+	require.NoError(t, db.Insert(ctx, p))
+	require.NoError(t, db.Insert(ctx, &noteModel{PersonID: p.GetID(), Body: "Shoots faster than his shadow"}))
+	require.NoError(t, db.InsertInto(ctx, "person_test_2024", &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}))
+
+	// Referencing tables are also truncated
+	assert.NoError(t, db.Truncate(ctx, "person_test", "public.person_test_2024"))
+	assert.Equal(t, 0, count(t, "person_test"))
+	assert.Equal(t, 0, count(t, "note_test"))
+	assert.Equal(t, 0, count(t, "person_test_2024"))
+
+	assert.Error(t, db.Truncate(ctx))
+	assert.Error(t, db.Truncate(ctx, "person_test; DROP TABLE person_test"))
+	assert.Error(t, db.Truncate(ctx, "missing_table"))
+
+	t.Run("fail read only", func(t *testing.T) {
+		db, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer db.Close()
+		assert.ErrorIs(t, db.Truncate(ctx, "person_test"), ErrReadOnly)
+	})
+}
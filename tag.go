@@ -0,0 +1,90 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/go-sqlx/sqlx"
+)
+
+type queryTagKey struct{}
+
+// WithQueryTag returns a new context with a tag that the DB methods will
+// prepend to their queries as an SQL comment, `/* tag */ SELECT ...`, making
+// them attributable to a code path in pg_stat_statements or in the database
+// logs. The DB methods tag their queries even when they run in the transaction
+// of the context, see RunInTx, but the queries run with the methods of a Tx
+// are not tagged.
+//
+// To prevent SQL injection, any character in the tag other than letters,
+// digits, spaces, and `_-.:=,/` is replaced by `_`.
+func WithQueryTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, queryTagKey{}, sanitizeQueryTag(tag))
+}
+
+// QueryTagFromContext returns the query tag in the context, if any.
+func QueryTagFromContext(ctx context.Context) (string, bool) {
+	tag, ok := ctx.Value(queryTagKey{}).(string)
+	return tag, ok && tag != ""
+}
+
+func sanitizeQueryTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune(" _-.:=,/", r):
+			return r
+		default:
+			return '_'
+		}
+	}, tag)
+}
+
+// tagQuery prepends the query tag in the context to the query.
+func tagQuery(ctx context.Context, query string) string {
+	if tag, ok := QueryTagFromContext(ctx); ok {
+		return "/* " + tag + " */ " + query
+	}
+	return query
+}
+
+// tagged returns an executor that prepends the query tag in the context to all
+// the queries. If there is no tag, it returns the given executor.
+func tagged(ctx context.Context, ex executor) executor {
+	if _, ok := QueryTagFromContext(ctx); ok {
+		return &tagExecutor{executor: ex}
+	}
+	return ex
+}
+
+// tagExecutor is an executor that prepends the query tag in the context of
+// each call to the query.
+type tagExecutor struct {
+	executor
+}
+
+func (e *tagExecutor) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return e.executor.QueryContext(ctx, tagQuery(ctx, query), args...)
+}
+
+func (e *tagExecutor) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return e.executor.QueryRowContext(ctx, tagQuery(ctx, query), args...)
+}
+
+func (e *tagExecutor) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	return e.executor.QueryxContext(ctx, tagQuery(ctx, query), args...)
+}
+
+func (e *tagExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return e.executor.ExecContext(ctx, tagQuery(ctx, query), args...)
+}
+
+func (e *tagExecutor) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return e.executor.GetContext(ctx, dest, tagQuery(ctx, query), args...)
+}
+
+func (e *tagExecutor) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return e.executor.SelectContext(ctx, dest, tagQuery(ctx, query), args...)
+}
@@ -0,0 +1,83 @@
+package sequel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithQueryTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		want    string
+		wantTag bool
+	}{
+		{"ok", "handler:CreateUser", "/* handler:CreateUser */ SELECT 1", true},
+		{"ok spaces", "service=api, route=/users", "/* service=api, route=/users */ SELECT 1", true},
+		{"ok injection", "foo */ DROP TABLE person_test; /*", "/* foo _/ DROP TABLE person_test_ /_ */ SELECT 1", true},
+		{"ok newline", "foo\n-- bar", "/* foo_-- bar */ SELECT 1", true},
+		{"ok empty", "", "SELECT 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithQueryTag(context.Background(), tt.tag)
+			_, ok := QueryTagFromContext(ctx)
+			assert.Equal(t, tt.wantTag, ok)
+			assert.Equal(t, tt.want, tagQuery(ctx, "SELECT 1"))
+		})
+	}
+
+	_, ok := QueryTagFromContext(context.Background())
+	assert.False(t, ok)
+	assert.Equal(t, "SELECT 1", tagQuery(context.Background(), "SELECT 1"))
+}
+
+func TestDB_queryTag(t *testing.T) {
+	const currentQuery = "SELECT query FROM pg_stat_activity WHERE pid = pg_backend_pid()"
+
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := WithQueryTag(context.Background(), "handler:CreateUser")
+
+	t.Run("get", func(t *testing.T) {
+		var query string
+		assert.NoError(t, db.Get(ctx, &query, currentQuery))
+		assert.Equal(t, "/* handler:CreateUser */ "+currentQuery, query)
+	})
+
+	t.Run("queryRow", func(t *testing.T) {
+		var query string
+		assert.NoError(t, db.QueryRow(ctx, currentQuery).Scan(&query))
+		assert.Equal(t, "/* handler:CreateUser */ "+currentQuery, query)
+	})
+
+	t.Run("getAll", func(t *testing.T) {
+		var queries []string
+		assert.NoError(t, db.GetAll(ctx, &queries, currentQuery))
+		assert.Equal(t, []string{"/* handler:CreateUser */ " + currentQuery}, queries)
+	})
+
+	t.Run("no tag", func(t *testing.T) {
+		var query string
+		assert.NoError(t, db.Get(context.Background(), &query, currentQuery))
+		assert.Equal(t, currentQuery, query)
+	})
+
+	t.Run("acquire timeout", func(t *testing.T) {
+		db, err := New(postgresDataSource, WithAcquireTimeout(time.Second))
+		require.NoError(t, err)
+		defer db.Close()
+
+		var query string
+		assert.NoError(t, db.Get(ctx, &query, currentQuery))
+		assert.Equal(t, "/* handler:CreateUser */ "+currentQuery, query)
+	})
+}
@@ -1,4 +1,5 @@
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp" WITH SCHEMA public;
+CREATE EXTENSION IF NOT EXISTS citext WITH SCHEMA public;
 
 CREATE TABLE person_test (
     id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
@@ -11,6 +12,18 @@ CREATE TABLE person_test (
 
 CREATE UNIQUE INDEX ON person_test(email);
 
+CREATE TABLE person_test_2024 (LIKE person_test INCLUDING ALL);
+
+CREATE TABLE note_test (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    updated_at timestamptz NOT NULL DEFAULT NOW(),
+    deleted_at timestamptz,
+    person_id uuid NOT NULL REFERENCES person_test(id) ON DELETE CASCADE,
+    body text NOT NULL,
+    search tsvector GENERATED ALWAYS AS (to_tsvector('simple', body)) STORED
+);
+
 CREATE TABLE array_test (
     id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
     created_at timestamptz NOT NULL DEFAULT NOW(),
@@ -19,5 +32,51 @@ CREATE TABLE array_test (
     cidrs cidr[],
     integers integer[],
     varchars varchar(255)[],
-    texts text[]
+    texts text[],
+    uuids uuid[]
+);
+
+CREATE TABLE generated_test (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    updated_at timestamptz NOT NULL DEFAULT NOW(),
+    deleted_at timestamptz,
+    sku varchar(255) NOT NULL,
+    price_cents integer NOT NULL,
+    quantity integer NOT NULL,
+    total_cents integer GENERATED ALWAYS AS (price_cents * quantity) STORED
 );
+
+CREATE UNIQUE INDEX ON generated_test(sku);
+
+CREATE TYPE address AS (
+    street text,
+    number integer
+);
+
+CREATE TYPE mood AS ENUM ('sad', 'ok', 'happy');
+
+CREATE TABLE types_test (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    updated_at timestamptz NOT NULL DEFAULT NOW(),
+    deleted_at timestamptz,
+    email citext,
+    address address,
+    settings jsonb,
+    duration interval,
+    data bytea,
+    ip inet,
+    network cidr,
+    price money
+);
+
+CREATE UNIQUE INDEX ON types_test(email);
+
+CREATE FUNCTION person_test_by_name(pattern text) RETURNS SETOF person_test AS $$
+    SELECT * FROM person_test WHERE name LIKE pattern AND deleted_at IS NULL ORDER BY name;
+$$ LANGUAGE sql STABLE;
+
+CREATE FUNCTION person_test_count(pattern text) RETURNS bigint AS $$
+    SELECT count(*) FROM person_test WHERE name LIKE pattern AND deleted_at IS NULL;
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,332 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sqlx/sqlx"
+)
+
+type txKey struct{}
+
+// NewTxContext returns a new context with the given transaction. RunInTx uses
+// the transaction in the context instead of starting a new one, and the other
+// DB methods run their queries in it.
+func NewTxContext(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction associated with this context.
+func TxFromContext(ctx context.Context) (tx *Tx, ok bool) {
+	tx, ok = ctx.Value(txKey{}).(*Tx)
+	return
+}
+
+// txExecutor is the executor used by the DB methods when the context contains a
+// transaction, it runs the queries in that transaction.
+type txExecutor struct {
+	tx *Tx
+}
+
+func (e *txExecutor) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return e.tx.tx.QueryContext(ctx, e.tx.track(query), args...)
+}
+
+func (e *txExecutor) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return e.tx.tx.QueryRowContext(ctx, e.tx.track(query), args...)
+}
+
+func (e *txExecutor) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	return e.tx.tx.QueryxContext(ctx, e.tx.track(query), args...)
+}
+
+func (e *txExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return e.tx.tx.ExecContext(ctx, e.tx.track(query), args...)
+}
+
+func (e *txExecutor) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return e.tx.tx.GetContext(ctx, dest, e.tx.track(query), args...)
+}
+
+func (e *txExecutor) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return e.tx.tx.SelectContext(ctx, dest, e.tx.track(query), args...)
+}
+
+// BeginTxx always fails, transactions cannot be nested, RunInTx uses savepoints
+// instead.
+func (e *txExecutor) BeginTxx(context.Context, *sql.TxOptions) (*sqlx.Tx, error) {
+	return nil, errors.New("cannot begin a transaction inside a transaction")
+}
+
+// RunInTx runs fn in a transaction. The transaction is committed if fn returns
+// nil and rolled back if fn returns an error or panics. The context passed to
+// fn contains the transaction and can be retrieved with TxFromContext.
+//
+// The DB methods called with the context passed to fn, or any context derived
+// from it, like db.Insert(ctx, m), run in the transaction too, so the same code
+// works both standalone and within a transaction. A Tx is not safe for
+// concurrent use, the context must not be shared with other goroutines.
+//
+// If the given context already contains a transaction, RunInTx does not start
+// a new one. Instead, it creates a savepoint in the ambient transaction that is
+// released if fn succeeds, or rolled back if fn fails, so only the work done by
+// fn is discarded. The outer transaction is still responsible for committing
+// all the work.
+//
+// If the last query tracking is enabled, see [WithLastQueryTracking], and fn
+// panics, the transaction is rolled back and RunInTx panics with a [*TxPanic]
+// that contains the original value and the last query started in the
+// transaction.
+//
+// The transaction is bounded by the given context, it is rolled back if the
+// context is done before the commit, see Tx.CommitContext. If a write timeout
+// is set, see [WithWriteTimeout], it bounds the whole transaction, the
+// transaction is rolled back if fn and the commit do not finish in time.
+func (d *DB) RunInTx(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) (err error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.runInSavepoint(ctx, fn)
+	}
+
+	if d.writeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.writeTimeout)
+		defer cancel()
+	}
+
+	tx, err := d.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			r = tx.wrapPanic(r)
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(NewTxContext(ctx, tx), tx); err != nil {
+		_ = tx.RollbackContext(ctx)
+		return err
+	}
+	return tx.CommitContext(ctx)
+}
+
+// TxPanic is the value RunInTx panics with if fn panics and the last query
+// tracking is enabled, see [WithLastQueryTracking].
+type TxPanic struct {
+	Value     any
+	LastQuery string
+}
+
+// Error implements the error interface on the TxPanic.
+func (p *TxPanic) Error() string {
+	return fmt.Sprintf("panic in transaction: %v; last query: %s", p.Value, p.LastQuery)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (p *TxPanic) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// wrapPanic returns a *TxPanic with the given panic value and the last query,
+// if the tracking is enabled and the value is not already a *TxPanic.
+func (t *Tx) wrapPanic(r any) any {
+	if _, ok := r.(*TxPanic); ok || t.tracker == nil {
+		return r
+	}
+	return &TxPanic{Value: r, LastQuery: t.LastQuery()}
+}
+
+// TxObserver is the type of the function called when a transaction ends, see
+// [WithTxObserver].
+type TxObserver func(d time.Duration, committed bool, err error)
+
+// end commits or rolls back the transaction with fn and, on the first call,
+// removes it from the open transactions and reports the outcome to the
+// observer, if any.
+func (t *Tx) end(commit bool, fn func() error) error {
+	err := fn()
+	t.ended.Store(true)
+	t.endOnce.Do(func() {
+		if t.openTxs != nil {
+			t.openTxs.Add(-1)
+		}
+		if t.observer != nil {
+			t.observer(t.clock.Since(t.started), commit && err == nil, err)
+		}
+	})
+	return err
+}
+
+// txLeakDetector logs a warning when a transaction is garbage collected without
+// being committed or rolled back, see [WithTxLeakDetection].
+type txLeakDetector struct {
+	logger *slog.Logger
+	stacks bool
+}
+
+// newTxLeakDetector returns a new txLeakDetector if enabled is true, nil
+// otherwise. If the logger is nil, the default one is used.
+func newTxLeakDetector(enabled, stacks bool, logger *slog.Logger) *txLeakDetector {
+	if !enabled {
+		return nil
+	}
+	return &txLeakDetector{logger: logger, stacks: stacks}
+}
+
+// watch sets a finalizer on the transaction that logs the leak. It does
+// nothing if the detector is nil.
+func (l *txLeakDetector) watch(t *Tx) {
+	if l == nil {
+		return
+	}
+	var stack string
+	if l.stacks {
+		stack = string(debug.Stack())
+	}
+	runtime.SetFinalizer(t, func(t *Tx) {
+		if t.ended.Load() {
+			return
+		}
+		logger := l.logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		attrs := []slog.Attr{
+			slog.Duration("age", t.clock.Since(t.started)),
+		}
+		if t.name != "" {
+			attrs = append(attrs, slog.String("name", t.name))
+		}
+		if stack != "" {
+			attrs = append(attrs, slog.String("stack", stack))
+		}
+		logger.LogAttrs(context.Background(), slog.LevelWarn, "transaction leaked without commit or rollback", attrs...)
+	})
+}
+
+// queryTracker holds the last query started in a transaction.
+type queryTracker struct {
+	mu    sync.Mutex
+	query string
+}
+
+// newQueryTracker returns a new queryTracker if enabled is true, nil otherwise.
+func newQueryTracker(enabled bool) *queryTracker {
+	if enabled {
+		return new(queryTracker)
+	}
+	return nil
+}
+
+// track prepends the name of the transaction, if any, to the given query and
+// records it as the last one in the transaction, if the tracking is enabled.
+// It returns the resulting query.
+func (t *Tx) track(query string) string {
+	if t.name != "" {
+		query = "/* tx:" + t.name + " */ " + query
+	}
+	if t.tracker != nil {
+		t.tracker.mu.Lock()
+		t.tracker.query = query
+		t.tracker.mu.Unlock()
+	}
+	return query
+}
+
+// Name returns the name of the transaction, empty unless it was started with
+// [DB.BeginNamed].
+func (t *Tx) Name() string {
+	return t.name
+}
+
+// LastQuery returns the last query started in the transaction. It always
+// returns an empty string unless the DB was created with
+// [WithLastQueryTracking].
+func (t *Tx) LastQuery() string {
+	if t.tracker == nil {
+		return ""
+	}
+	t.tracker.mu.Lock()
+	defer t.tracker.mu.Unlock()
+	return t.tracker.query
+}
+
+// runInSavepoint runs fn in a new savepoint of the transaction.
+func (t *Tx) runInSavepoint(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	t.savepoints++
+	name := fmt.Sprintf("sequel_sp_%d", t.savepoints)
+	defer func() {
+		t.savepoints--
+	}()
+
+	if err := t.Savepoint(name); err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			r = t.wrapPanic(r)
+			_ = t.RollbackTo(name)
+			panic(r)
+		}
+	}()
+
+	if err := fn(ctx, t); err != nil {
+		if rerr := t.RollbackTo(name); rerr != nil {
+			return fmt.Errorf("%w; error rolling back to savepoint: %w", err, rerr)
+		}
+		return err
+	}
+	return t.Release(name)
+}
+
+// Savepoint creates a savepoint with the given name in the transaction.
+func (t *Tx) Savepoint(name string) error {
+	return t.savepointExec("SAVEPOINT ", name)
+}
+
+// RollbackTo rolls back all the work done in the transaction after the
+// savepoint with the given name was created. The savepoint remains valid.
+func (t *Tx) RollbackTo(name string) error {
+	return t.savepointExec("ROLLBACK TO SAVEPOINT ", name)
+}
+
+// Release destroys the savepoint with the given name, keeping the work done
+// after it was created.
+func (t *Tx) Release(name string) error {
+	return t.savepointExec("RELEASE SAVEPOINT ", name)
+}
+
+func (t *Tx) savepointExec(cmd, name string) error {
+	if !isIdentifier(name) || strings.Contains(name, ".") {
+		return fmt.Errorf("invalid savepoint name %q", name)
+	}
+	_, err := t.tx.Exec(t.track(cmd + name))
+	return err
+}
+
+// SetLocal sets the given run-time parameter like `SET LOCAL`, for example, to
+// tune the query planner with `tx.SetLocal("enable_seqscan", "off")`. The
+// setting only lasts until the end of the transaction, or the savepoint if it
+// is rolled back.
+//
+// The parameter must be a valid, optionally qualified, identifier. Both the
+// parameter and the value are sent as arguments of `set_config(param, value,
+// true)`, the function form of `SET LOCAL`.
+func (t *Tx) SetLocal(param, value string) error {
+	if !isIdentifier(param) {
+		return fmt.Errorf("invalid parameter name %q", param)
+	}
+	_, err := t.tx.Exec(t.track(t.Rebind("SELECT set_config(?, ?, true)")), param, value)
+	return err
+}
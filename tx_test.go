@@ -0,0 +1,623 @@
+package sequel
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/sequel/clock"
+)
+
+func TestDB_RunInTx(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	clearTable := func(t *testing.T) {
+		t.Helper()
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		require.NoError(t, err)
+	}
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	count := func(t *testing.T) int {
+		t.Helper()
+		var n int
+		require.NoError(t, db.Get(ctx, &n, "SELECT count(*) FROM person_test"))
+		return n
+	}
+
+	errTest := errors.New("test error")
+
+	t.Run("ok", func(t *testing.T) {
+		t.Cleanup(func() { clearTable(t) })
+		p := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+		assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			ctxTx, ok := TxFromContext(ctx)
+			assert.True(t, ok)
+			assert.Same(t, tx, ctxTx)
+			return tx.Insert(p)
+		}))
+
+		var got personModel
+		assert.NoError(t, db.Select(ctx, &got, p.GetID()))
+		assertEqualPerson(t, p, &got)
+	})
+
+	t.Run("ok nested", func(t *testing.T) {
+		t.Cleanup(func() { clearTable(t) })
+		assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			if err := tx.Insert(&personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}); err != nil {
+				return err
+			}
+			// Inner success
+			if err := db.RunInTx(ctx, func(ctx context.Context, inner *Tx) error {
+				assert.Same(t, tx, inner)
+				return inner.Insert(&personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")})
+			}); err != nil {
+				return err
+			}
+			// Inner failure, only its own work is rolled back
+			err := db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+				if err := tx.Insert(&personModel{Name: "William Dalton", Email: NullString("william@example.com")}); err != nil {
+					return err
+				}
+				// Nested savepoint
+				assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+					return tx.Insert(&personModel{Name: "Averell Dalton", Email: NullString("averell@example.com")})
+				}))
+				return errTest
+			})
+			assert.ErrorIs(t, err, errTest)
+			// Unique violation aborts the savepoint but not the transaction
+			err = db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+				return tx.Insert(&personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")})
+			})
+			assert.True(t, IsUniqueViolation(err))
+			return nil
+		}))
+
+		var names []string
+		assert.NoError(t, db.GetAll(ctx, &names, "SELECT name FROM person_test ORDER BY name"))
+		assert.Equal(t, []string{"Jack Dalton", "Joe Dalton"}, names)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		t.Cleanup(func() { clearTable(t) })
+		err := db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+				return tx.Insert(&personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")})
+			}))
+			return errTest
+		})
+		assert.ErrorIs(t, err, errTest)
+		assert.Equal(t, 0, count(t))
+	})
+
+	t.Run("fail panic", func(t *testing.T) {
+		t.Cleanup(func() { clearTable(t) })
+		assert.PanicsWithValue(t, "test panic", func() {
+			_ = db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+				if err := tx.Insert(&personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}); err != nil {
+					return err
+				}
+				panic("test panic")
+			})
+		})
+		assert.Equal(t, 0, count(t))
+	})
+
+	t.Run("ok ambient", func(t *testing.T) {
+		t.Cleanup(func() { clearTable(t) })
+		p1 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+		p2 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+		assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			// The DB methods run in the transaction.
+			if err := db.Insert(ctx, p1); err != nil {
+				return err
+			}
+			if err := db.InsertBatch(ctx, []Model{p2}); err != nil {
+				return err
+			}
+			var n int
+			if err := tx.Get(&n, "SELECT count(*) FROM person_test"); err != nil {
+				return err
+			}
+			assert.Equal(t, 2, n)
+			assert.Equal(t, 0, count(t))
+
+			p1.Name = "Joe Dalton Sr."
+			if err := db.Update(ctx, p1); err != nil {
+				return err
+			}
+			var got personModel
+			if err := db.Select(ctx, &got, p1.GetID()); err != nil {
+				return err
+			}
+			assert.Equal(t, "Joe Dalton Sr.", got.Name)
+			return db.HardDelete(ctx, &personModelExtra{personModel: *p2})
+		}))
+
+		var names []string
+		assert.NoError(t, db.GetAll(ctx, &names, "SELECT name FROM person_test ORDER BY name"))
+		assert.Equal(t, []string{"Joe Dalton Sr."}, names)
+
+		// Work done with the DB methods is also rolled back.
+		assert.ErrorIs(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			if err := db.Delete(ctx, p1); err != nil {
+				return err
+			}
+			return errTest
+		}), errTest)
+		assert.Equal(t, 1, count(t))
+	})
+
+	t.Run("fail read only", func(t *testing.T) {
+		db, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer db.Close()
+		assert.ErrorIs(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			return nil
+		}), ErrReadOnly)
+	})
+}
+
+func TestTx_Savepoint(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, tx.Rollback())
+	}()
+
+	p1 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	p2 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+	p3 := &personModel{Name: "William Dalton", Email: NullString("william@example.com")}
+
+	require.NoError(t, tx.Insert(p1))
+	assert.NoError(t, tx.Savepoint("sp1"))
+	require.NoError(t, tx.Insert(p2))
+	assert.NoError(t, tx.Savepoint("sp2"))
+	require.NoError(t, tx.Insert(p3))
+	assert.NoError(t, tx.Release("sp2"))
+	assert.NoError(t, tx.RollbackTo("sp1"))
+
+	var p personModel
+	assert.NoError(t, tx.Select(&p, p1.GetID()))
+	assert.ErrorIs(t, tx.Select(&p, p2.GetID()), sql.ErrNoRows)
+	assert.ErrorIs(t, tx.Select(&p, p3.GetID()), sql.ErrNoRows)
+
+	assert.Error(t, tx.Savepoint("sp; DROP TABLE person_test"))
+	assert.Error(t, tx.Savepoint("public.sp"))
+	assert.Error(t, tx.Release("sp2"))
+}
+
+func TestDB_lastQueryTracking(t *testing.T) {
+	db, err := New(postgresDataSource, WithLastQueryTracking())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("lastQuery", func(t *testing.T) {
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		assert.Empty(t, tx.LastQuery())
+		p := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+		require.NoError(t, tx.Insert(p))
+		assert.Contains(t, tx.LastQuery(), "INSERT INTO person_test")
+		assert.NoError(t, tx.Select(p, p.GetID()))
+		assert.Equal(t, personSelectQ, tx.LastQuery())
+		_, err = tx.RebindExec("SELECT pg_sleep(?)", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "SELECT pg_sleep($1)", tx.LastQuery())
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		defer func() {
+			r := recover()
+			var p *TxPanic
+			require.ErrorAs(t, r.(error), &p)
+			assert.Equal(t, "test panic", p.Value)
+			assert.Equal(t, "SELECT 1", p.LastQuery)
+			assert.Equal(t, "panic in transaction: test panic; last query: SELECT 1", p.Error())
+		}()
+		_ = db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			if _, err := tx.Exec("SELECT 1"); err != nil {
+				return err
+			}
+			panic("test panic")
+		})
+	})
+
+	t.Run("panic in savepoint", func(t *testing.T) {
+		errPanic := errors.New("test panic")
+		defer func() {
+			r := recover()
+			var p *TxPanic
+			require.ErrorAs(t, r.(error), &p)
+			assert.ErrorIs(t, p, errPanic)
+			assert.Equal(t, "SELECT 2", p.LastQuery)
+		}()
+		_ = db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			return db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+				if _, err := tx.Exec("SELECT 2"); err != nil {
+					return err
+				}
+				panic(errPanic)
+			})
+		})
+	})
+
+	t.Run("named", func(t *testing.T) {
+		tx, err := db.BeginNamed(ctx, "CreateOrder; */ DROP")
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		assert.Equal(t, "CreateOrder_ _/ DROP", tx.Name())
+		var query string
+		require.NoError(t, tx.Get(&query, "SELECT query FROM pg_stat_activity WHERE pid = pg_backend_pid()"))
+		assert.Equal(t, "/* tx:CreateOrder_ _/ DROP */ SELECT query FROM pg_stat_activity WHERE pid = pg_backend_pid()", query)
+		assert.Equal(t, query, tx.LastQuery())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		db, err := New(postgresDataSource)
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		_, err = tx.Exec("SELECT 1")
+		assert.NoError(t, err)
+		assert.Empty(t, tx.LastQuery())
+	})
+}
+
+func TestTx_CommitContext(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		p := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+		require.NoError(t, tx.Insert(p))
+		assert.NoError(t, tx.CommitContext(ctx))
+		assert.NoError(t, db.Select(ctx, &personModel{}, p.GetID()))
+	})
+
+	t.Run("ok rollback", func(t *testing.T) {
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		p := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+		require.NoError(t, tx.Insert(p))
+		assert.NoError(t, tx.RollbackContext(ctx))
+		assert.ErrorIs(t, db.Select(ctx, &personModel{}, p.GetID()), sql.ErrNoRows)
+	})
+
+	t.Run("fail canceled", func(t *testing.T) {
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		p := &personModel{Name: "William Dalton", Email: NullString("william@example.com")}
+		require.NoError(t, tx.Insert(p))
+
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.ErrorIs(t, tx.CommitContext(cctx), context.Canceled)
+		assert.ErrorIs(t, tx.Rollback(), sql.ErrTxDone)
+		assert.ErrorIs(t, db.Select(ctx, &personModel{}, p.GetID()), sql.ErrNoRows)
+	})
+}
+
+func TestTx_SetLocal(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	currentSetting := func(t *testing.T, q interface {
+		QueryRow(string, ...any) *sql.Row
+	}) string {
+		t.Helper()
+		var s string
+		require.NoError(t, q.QueryRow("SELECT current_setting('enable_seqscan')").Scan(&s))
+		return s
+	}
+
+	tx, err := db.Begin(ctx)
+	require.NoError(t, err)
+	assert.NoError(t, tx.SetLocal("enable_seqscan", "off"))
+	assert.Equal(t, "off", currentSetting(t, tx.Underlying().Tx))
+	assert.NoError(t, tx.SetLocal("application_name", `it\'s a test`))
+	var name string
+	require.NoError(t, tx.QueryRow("SELECT current_setting('application_name')").Scan(&name))
+	assert.Equal(t, `it\'s a test`, name)
+	assert.NoError(t, tx.Commit())
+
+	// The setting does not outlive the transaction.
+	tx, err = db.Begin(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "on", currentSetting(t, tx.Underlying().Tx))
+
+	assert.Error(t, tx.SetLocal("enable_seqscan = off; DROP TABLE person_test; --", "on"))
+	assert.Error(t, tx.SetLocal("", "on"))
+	assert.Error(t, tx.SetLocal("enable_seqscan", "off\x00"))
+	assert.Error(t, tx.SetLocal("not_a_parameter", "on"))
+	assert.NoError(t, tx.Rollback())
+}
+
+func TestDB_txObserver(t *testing.T) {
+	type observation struct {
+		d         time.Duration
+		committed bool
+		err       error
+	}
+	var mu sync.Mutex
+	var observed []observation
+	last := func(t *testing.T) observation {
+		t.Helper()
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, observed)
+		return observed[len(observed)-1]
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(observed)
+	}
+
+	now := time.Now()
+	var nowMu sync.Mutex
+	nowFunc := func() time.Time {
+		nowMu.Lock()
+		defer nowMu.Unlock()
+		now = now.Add(time.Second)
+		return now
+	}
+
+	db, err := New(postgresDataSource, WithNowFunc(nowFunc), WithTxObserver(func(d time.Duration, committed bool, err error) {
+		mu.Lock()
+		observed = append(observed, observation{d, committed, err})
+		mu.Unlock()
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	errTest := errors.New("test error")
+
+	t.Run("commit", func(t *testing.T) {
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		assert.Equal(t, observation{time.Second, true, nil}, last(t))
+
+		// Only the first call is observed
+		assert.ErrorIs(t, tx.Rollback(), sql.ErrTxDone)
+		assert.Equal(t, 1, count())
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		n := count()
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		nowFunc()
+		require.NoError(t, tx.RollbackContext(ctx))
+		assert.Equal(t, observation{2 * time.Second, false, nil}, last(t))
+		assert.Equal(t, n+1, count())
+	})
+
+	t.Run("runInTx", func(t *testing.T) {
+		n := count()
+		assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			// Savepoints do not end the transaction
+			return db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+				return nil
+			})
+		}))
+		assert.True(t, last(t).committed)
+		assert.ErrorIs(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			return errTest
+		}), errTest)
+		assert.False(t, last(t).committed)
+		assert.NoError(t, last(t).err)
+		assert.Equal(t, n+2, count())
+	})
+
+	t.Run("commit error", func(t *testing.T) {
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		_, err = tx.Exec("SELECT 1/0")
+		assert.Error(t, err)
+		// A commit of an aborted transaction is a rollback
+		err = tx.Commit()
+		assert.Error(t, err)
+		o := last(t)
+		assert.False(t, o.committed)
+		assert.Equal(t, err, o.err)
+	})
+
+	t.Run("conn", func(t *testing.T) {
+		n := count()
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err)
+		defer conn.Close()
+		tx, err := conn.Begin(ctx)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		assert.True(t, last(t).committed)
+		assert.Equal(t, n+1, count())
+	})
+}
+
+func TestDB_OpenTransactions(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	assert.Equal(t, 0, db.OpenTransactions())
+
+	tx1, err := db.Begin(ctx)
+	require.NoError(t, err)
+	tx2, err := db.BeginReadOnly(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, db.OpenTransactions())
+
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+	tx3, err := conn.Begin(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, db.OpenTransactions())
+
+	assert.NoError(t, tx1.Commit())
+	assert.Equal(t, 2, db.OpenTransactions())
+	assert.NoError(t, tx2.Rollback())
+	assert.Error(t, tx2.Rollback())
+	assert.Equal(t, 1, db.OpenTransactions())
+	assert.NoError(t, tx3.Rollback())
+	assert.Equal(t, 0, db.OpenTransactions())
+
+	assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+		assert.Equal(t, 1, db.OpenTransactions())
+		return db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+			assert.Equal(t, 1, db.OpenTransactions())
+			return nil
+		})
+	}))
+	assert.Error(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+		_, err := tx.Exec("SELECT * FROM missing_table")
+		return err
+	}))
+	assert.Equal(t, 0, db.OpenTransactions())
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func Test_txLeakDetector(t *testing.T) {
+	var buf syncBuffer
+	l := newTxLeakDetector(true, true, slog.New(slog.NewTextHandler(&buf, nil)))
+
+	// The transactions must be unreachable after this call.
+	func() {
+		leaked := &Tx{clock: clock.New(), started: time.Now(), name: "leaky"}
+		l.watch(leaked)
+		ended := &Tx{clock: clock.New(), started: time.Now(), name: "ended"}
+		l.watch(ended)
+		ended.ended.Store(true)
+	}()
+
+	assert.Eventually(t, func() bool {
+		runtime.GC()
+		return buf.String() != ""
+	}, 5*time.Second, 10*time.Millisecond)
+	// Give time to the other finalizer.
+	runtime.GC()
+	time.Sleep(100 * time.Millisecond)
+
+	got := buf.String()
+	assert.Equal(t, 1, strings.Count(got, "msg="))
+	assert.Contains(t, got, `level=WARN msg="transaction leaked without commit or rollback"`)
+	assert.Contains(t, got, "name=leaky")
+	assert.Contains(t, got, "Test_txLeakDetector")
+
+	assert.Nil(t, newTxLeakDetector(false, true, nil))
+	assert.NotPanics(t, func() {
+		var l *txLeakDetector
+		l.watch(&Tx{})
+	})
+}
+
+func TestDB_txLeakDetection(t *testing.T) {
+	var buf syncBuffer
+	sqlDB, err := sql.Open("pgx/v5", postgresDataSource)
+	require.NoError(t, err)
+	db, err := NewDB(sqlDB, "pgx/v5", WithTxLeakDetection(slog.New(slog.NewTextHandler(&buf, nil)), false))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	func() {
+		tx, err := db.BeginNamed(ctx, "leaky")
+		require.NoError(t, err)
+		_, err = tx.Exec("SELECT 1")
+		require.NoError(t, err)
+	}()
+	assert.NoError(t, db.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+		return nil
+	}))
+
+	assert.Eventually(t, func() bool {
+		runtime.GC()
+		return strings.Contains(buf.String(), "transaction leaked")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, buf.String(), "name=leaky")
+	assert.NotContains(t, buf.String(), "stack=")
+	assert.Equal(t, 1, db.OpenTransactions())
+}
@@ -0,0 +1,438 @@
+package sequel
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CIText is a string type for case-insensitive citext columns.
+type CIText string
+
+// Scan implements the sql.Scanner interface on the CIText. A NULL value is
+// scanned as an empty string.
+func (t *CIText) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t = ""
+	case string:
+		*t = CIText(v)
+	case []byte:
+		*t = CIText(v)
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface on the CIText.
+func (t CIText) Value() (driver.Value, error) {
+	return string(t), nil
+}
+
+// String returns the CIText as a string.
+func (t CIText) String() string {
+	return string(t)
+}
+
+// Equal reports whether t and s are equal under simple Unicode case-folding,
+// the same way a citext column compares them.
+func (t CIText) Equal(s string) bool {
+	return strings.EqualFold(string(t), s)
+}
+
+const (
+	microsecondsPerDay   = int64(24 * time.Hour / time.Microsecond)
+	microsecondsPerMonth = 30 * microsecondsPerDay
+)
+
+// scalarMap is the type map used to encode and decode the scalar types in this
+// package. It is separate from the type maps used by [Array] and [Composite],
+// so these types can be used as their elements or fields, as those maps are
+// locked while they are encoded or decoded.
+var (
+	scalarMap   = pgtype.NewMap()
+	scalarMapMu sync.Mutex
+)
+
+// Duration is a time.Duration type for interval columns. Intervals are
+// converted to durations counting a day as 24 hours and a month as 30 days,
+// and durations are stored with microsecond precision, the precision of an
+// interval. A NULL value is scanned as 0.
+type Duration time.Duration
+
+// Scan implements the sql.Scanner interface on the Duration.
+func (d *Duration) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*d = 0
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+
+	var i pgtype.Interval
+	scalarMapMu.Lock()
+	err := scalarMap.Scan(pgtype.IntervalOID, pgtype.TextFormatCode, b, &i)
+	scalarMapMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	us := i.Microseconds + int64(i.Days)*microsecondsPerDay + int64(i.Months)*microsecondsPerMonth
+	*d = Duration(time.Duration(us) * time.Microsecond)
+	return nil
+}
+
+// Value implements the driver.Valuer interface on the Duration.
+func (d Duration) Value() (driver.Value, error) {
+	scalarMapMu.Lock()
+	defer scalarMapMu.Unlock()
+	buf, err := scalarMap.Encode(pgtype.IntervalOID, pgtype.TextFormatCode, pgtype.Interval{
+		Microseconds: time.Duration(d).Microseconds(),
+		Valid:        true,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return string(buf), nil
+}
+
+// String returns the Duration formatted as a time.Duration.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// Timestamp is a time.Time type for timestamp and timestamptz columns that
+// normalizes the time to the precision of those columns, microseconds, and to
+// UTC. A Timestamp created with NewTimestamp compares equal to the value read
+// back from the database. A NULL value is scanned as the zero Timestamp, and
+// the zero Timestamp is stored as NULL.
+type Timestamp struct {
+	time.Time
+}
+
+// NewTimestamp returns the given time as a Timestamp truncated to microseconds
+// in UTC.
+func NewTimestamp(t time.Time) Timestamp {
+	if t.IsZero() {
+		return Timestamp{}
+	}
+	return Timestamp{Time: t.Truncate(time.Microsecond).UTC()}
+}
+
+// Scan implements the sql.Scanner interface on the Timestamp.
+func (t *Timestamp) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*t = Timestamp{}
+		return nil
+	case time.Time:
+		*t = NewTimestamp(v)
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+
+	// Values of timestamp columns do not have a time zone, and they are
+	// scanned as UTC.
+	var ts pgtype.Timestamptz
+	scalarMapMu.Lock()
+	err := scalarMap.Scan(pgtype.TimestamptzOID, pgtype.TextFormatCode, b, &ts)
+	if err != nil {
+		var tsz pgtype.Timestamp
+		if scalarMap.Scan(pgtype.TimestampOID, pgtype.TextFormatCode, b, &tsz) == nil {
+			ts, err = pgtype.Timestamptz{Time: tsz.Time, InfinityModifier: tsz.InfinityModifier, Valid: tsz.Valid}, nil
+		}
+	}
+	scalarMapMu.Unlock()
+	if err != nil {
+		return err
+	}
+	if ts.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("cannot scan infinite timestamp %q", b)
+	}
+	*t = NewTimestamp(ts.Time)
+	return nil
+}
+
+// Value implements the driver.Valuer interface on the Timestamp.
+func (t Timestamp) Value() (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return t.Time.Truncate(time.Microsecond).UTC(), nil
+}
+
+// Inet is a netip.Addr type for inet columns with a single host address. A NULL
+// value is scanned as the zero Addr, and the zero Addr is stored as NULL.
+// Scanning an inet value with a network mask, like 192.168.0.1/24, fails, use
+// [CIDR] for those values.
+type Inet netip.Addr
+
+// Scan implements the sql.Scanner interface on the Inet.
+func (i *Inet) Scan(src any) error {
+	var addr netip.Addr
+	if err := scanInet(src, &addr); err != nil {
+		return err
+	}
+	*i = Inet(addr)
+	return nil
+}
+
+// Value implements the driver.Valuer interface on the Inet.
+func (i Inet) Value() (driver.Value, error) {
+	if !netip.Addr(i).IsValid() {
+		return nil, nil
+	}
+	return netip.Addr(i).String(), nil
+}
+
+// Addr returns the Inet as a netip.Addr.
+func (i Inet) Addr() netip.Addr {
+	return netip.Addr(i)
+}
+
+// String returns the Inet formatted as a netip.Addr.
+func (i Inet) String() string {
+	return netip.Addr(i).String()
+}
+
+// CIDR is a netip.Prefix type for cidr columns, it can also be used for inet
+// columns with a network mask. A NULL value is scanned as the zero Prefix, and
+// the zero Prefix is stored as NULL.
+type CIDR netip.Prefix
+
+// Scan implements the sql.Scanner interface on the CIDR.
+func (c *CIDR) Scan(src any) error {
+	var prefix netip.Prefix
+	if err := scanInet(src, &prefix); err != nil {
+		return err
+	}
+	*c = CIDR(prefix)
+	return nil
+}
+
+// Value implements the driver.Valuer interface on the CIDR.
+func (c CIDR) Value() (driver.Value, error) {
+	if !netip.Prefix(c).IsValid() {
+		return nil, nil
+	}
+	return netip.Prefix(c).String(), nil
+}
+
+// Prefix returns the CIDR as a netip.Prefix.
+func (c CIDR) Prefix() netip.Prefix {
+	return netip.Prefix(c)
+}
+
+// String returns the CIDR formatted as a netip.Prefix.
+func (c CIDR) String() string {
+	return netip.Prefix(c).String()
+}
+
+// scanInet scans the given inet or cidr value in the destination, a
+// *netip.Addr or a *netip.Prefix.
+func scanInet(src, dest any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+
+	scalarMapMu.Lock()
+	defer scalarMapMu.Unlock()
+	return scalarMap.Scan(pgtype.InetOID, pgtype.TextFormatCode, b, dest)
+}
+
+// Money is an int64 type for money columns that holds the amount in cents.
+// Money values are formatted by the database using the lc_monetary setting,
+// like $1,234.56, so scanning ignores the currency symbols and the group
+// separators, and it requires the amounts to have two fractional digits, the
+// default for most locales. A NULL value is scanned as 0.
+type Money int64
+
+// Scan implements the sql.Scanner interface on the Money.
+func (m *Money) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+
+	cents, err := parseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = Money(cents)
+	return nil
+}
+
+// Value implements the driver.Valuer interface on the Money.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// String returns the Money as a decimal number with two fractional digits,
+// e.g. 1234.56.
+func (m Money) String() string {
+	sign, v := "", uint64(m)
+	if m < 0 {
+		sign, v = "-", uint64(-m)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// parseMoney parses the text representation of a money value and returns the
+// amount in cents.
+func parseMoney(s string) (int64, error) {
+	var neg bool
+	var digits []byte
+	fracDigits := -1
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case isDigit(c):
+			digits = append(digits, c)
+			if fracDigits >= 0 {
+				fracDigits++
+			}
+		case c == '-' || c == '(':
+			neg = true
+		case c == '.' || c == ',':
+			fracDigits = 0
+		}
+	}
+
+	if len(digits) == 0 {
+		return 0, fmt.Errorf("invalid money value %q", s)
+	}
+	switch fracDigits {
+	case -1:
+		digits = append(digits, '0', '0')
+	case 2:
+	default:
+		return 0, fmt.Errorf("unsupported money value %q", s)
+	}
+
+	cents, err := strconv.ParseInt(string(digits), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid money value %q: %w", s, err)
+	}
+	if neg {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// ErrTooLarge is the error returned when a value exceeds the maximum size of its
+// type, like the one of [LimitedBytes].
+var ErrTooLarge = errors.New("value exceeds the maximum size")
+
+// Bytes is a []byte type for bytea columns. A NULL value is scanned as nil and a
+// nil Bytes is stored as NULL.
+type Bytes []byte
+
+// Scan implements the sql.Scanner interface on the Bytes.
+func (b *Bytes) Scan(src any) error {
+	v, err := scanBytes(src)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// Value implements the driver.Valuer interface on the Bytes.
+func (b Bytes) Value() (driver.Value, error) {
+	if b == nil {
+		return nil, nil
+	}
+	return []byte(b), nil
+}
+
+// SizeLimit is the interface that defines the maximum size in bytes of a
+// [LimitedBytes].
+type SizeLimit interface {
+	MaxSize() int
+}
+
+// LimitedBytes is a []byte type for bytea columns, like [Bytes], with a maximum
+// size defined by L. The Value method fails with [ErrTooLarge] if the value
+// exceeds that size, so the value is never sent to the database. For example:
+//
+//	type avatarLimit struct{}
+//
+//	func (avatarLimit) MaxSize() int { return 64 << 10 }
+//
+//	type User struct {
+//		sequel.Base `dbtable:"users"`
+//		Avatar      sequel.LimitedBytes[avatarLimit] `db:"avatar"`
+//	}
+type LimitedBytes[L SizeLimit] []byte
+
+// Scan implements the sql.Scanner interface on the LimitedBytes. The size of the
+// scanned value is not checked.
+func (b *LimitedBytes[L]) Scan(src any) error {
+	v, err := scanBytes(src)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// Value implements the driver.Valuer interface on the LimitedBytes.
+func (b LimitedBytes[L]) Value() (driver.Value, error) {
+	var l L
+	if n := l.MaxSize(); len(b) > n {
+		return nil, fmt.Errorf("%w: %d bytes, the maximum is %d", ErrTooLarge, len(b), n)
+	}
+	if b == nil {
+		return nil, nil
+	}
+	return []byte(b), nil
+}
+
+// scanBytes returns a copy of the given bytea value, as the driver may reuse the
+// source buffer.
+func scanBytes(src any) ([]byte, error) {
+	switch v := src.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return append([]byte{}, v...), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+}
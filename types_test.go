@@ -0,0 +1,512 @@
+package sequel
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/qb"
+)
+
+var typesSelectQ, typesInsertQ, typesUpdateQ, typesDeleteQ string
+
+func init() {
+	builder := qb.Must(&typesModel{})
+	typesSelectQ, typesInsertQ, typesUpdateQ, typesDeleteQ = Queries(builder)
+}
+
+type typesModel struct {
+	Base     `dbtable:"types_test"`
+	Email    CIText                  `db:"email"`
+	Address  Composite[address]      `db:"address"`
+	Settings NullJSON[typesSettings] `db:"settings"`
+	Duration Duration                `db:"duration"`
+	Data     Bytes                   `db:"data"`
+	IP       Inet                    `db:"ip"`
+	Network  CIDR                    `db:"network"`
+	Price    Money                   `db:"price"`
+}
+
+type typesSettings struct {
+	Theme  string   `json:"theme,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+type address struct {
+	Street string
+	Number int32
+}
+
+func (m *typesModel) Select() string { return typesSelectQ }
+func (m *typesModel) Insert() string { return typesInsertQ }
+func (m *typesModel) Update() string { return typesUpdateQ }
+func (m *typesModel) Delete() string { return typesDeleteQ }
+
+func assertEqualTypes(t *testing.T, want, got *typesModel) bool {
+	t.Helper()
+	got.CreatedAt = got.CreatedAt.UTC().Truncate(time.Second)
+	got.UpdatedAt = got.UpdatedAt.UTC().Truncate(time.Second)
+	want.CreatedAt = want.CreatedAt.Truncate(time.Second)
+	want.UpdatedAt = want.UpdatedAt.Truncate(time.Second)
+	return assert.Equal(t, want, got)
+}
+
+func TestTypes(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM types_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("citext", func(t *testing.T) {
+		m := &typesModel{Email: "Lucky.Luke@Example.com"}
+		require.NoError(t, db.Insert(ctx, m))
+
+		var got typesModel
+		assert.NoError(t, db.Select(ctx, &got, m.GetID()))
+		assertEqualTypes(t, m, &got)
+
+		var byEmail typesModel
+		assert.NoError(t, db.Get(ctx, &byEmail, "SELECT * FROM types_test WHERE email = $1", CIText("lucky.luke@example.com")))
+		assertEqualTypes(t, m, &byEmail)
+		assert.True(t, byEmail.Email.Equal("LUCKY.LUKE@EXAMPLE.COM"))
+
+		err := db.Insert(ctx, &typesModel{Email: "LUCKY.LUKE@EXAMPLE.COM"})
+		assert.True(t, IsUniqueViolation(err))
+	})
+
+	t.Run("composite", func(t *testing.T) {
+		require.NoError(t, db.LoadType(ctx, "address"))
+		RegisterDefaultPgType(address{}, "address")
+
+		m := &typesModel{
+			Email:   "joe.dalton@example.com",
+			Address: NewComposite(address{Street: "Main Street, Daisy Town", Number: 42}),
+		}
+		require.NoError(t, db.Insert(ctx, m))
+
+		var got typesModel
+		assert.NoError(t, db.Select(ctx, &got, m.GetID()))
+		assertEqualTypes(t, m, &got)
+
+		var street string
+		assert.NoError(t, db.Get(ctx, &street, "SELECT (address).street FROM types_test WHERE id = $1", m.GetID()))
+		assert.Equal(t, "Main Street, Daisy Town", street)
+
+		var a Composite[address]
+		assert.NoError(t, db.Get(ctx, &a, "SELECT ROW('Saloon', 7)::address"))
+		assert.Equal(t, NewComposite(address{Street: "Saloon", Number: 7}), a)
+		assert.NoError(t, db.Get(ctx, &a, "SELECT NULL::address"))
+		assert.False(t, a.Valid)
+
+		assert.Error(t, db.LoadType(ctx, "missing_type"))
+	})
+
+	t.Run("nullJSON", func(t *testing.T) {
+		m1 := &typesModel{
+			Email:    "jack.dalton@example.com",
+			Settings: NewNullJSON(typesSettings{Theme: "dark", Labels: []string{"foo", "bar"}}),
+		}
+		m2 := &typesModel{
+			Email:    "william.dalton@example.com",
+			Settings: NewNullJSON(typesSettings{}),
+		}
+		m3 := &typesModel{
+			Email: "averell.dalton@example.com",
+		}
+		require.NoError(t, db.InsertBatch(ctx, []Model{m1, m2, m3}))
+
+		for _, m := range []*typesModel{m1, m2, m3} {
+			var got typesModel
+			assert.NoError(t, db.Select(ctx, &got, m.GetID()))
+			assertEqualTypes(t, m, &got)
+		}
+
+		var n int
+		assert.NoError(t, db.Get(ctx, &n, "SELECT count(*) FROM types_test WHERE settings IS NULL"))
+		assert.GreaterOrEqual(t, n, 1)
+		var settings string
+		assert.NoError(t, db.Get(ctx, &settings, "SELECT settings::text FROM types_test WHERE id = $1", m2.GetID()))
+		assert.Equal(t, "{}", settings)
+		assert.NoError(t, db.Get(ctx, &settings, "SELECT settings->>'theme' FROM types_test WHERE id = $1", m1.GetID()))
+		assert.Equal(t, "dark", settings)
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		for i, d := range []time.Duration{
+			1500 * time.Millisecond, 50*time.Hour + 30*time.Minute + time.Microsecond, -90 * time.Minute,
+		} {
+			m := &typesModel{
+				Email:    CIText(fmt.Sprintf("duration%d@example.com", i)),
+				Duration: Duration(d),
+			}
+			require.NoError(t, db.Insert(ctx, m))
+
+			var got typesModel
+			assert.NoError(t, db.Select(ctx, &got, m.GetID()))
+			assertEqualTypes(t, m, &got)
+		}
+
+		var d Duration
+		assert.NoError(t, db.Get(ctx, &d, "SELECT '1 mon 2 days 03:04:05.6'::interval"))
+		assert.Equal(t, Duration(32*24*time.Hour+3*time.Hour+4*time.Minute+5600*time.Millisecond), d)
+
+		var s string
+		assert.NoError(t, db.Get(ctx, &s, "SELECT $1::interval::text", Duration(-90*time.Minute)))
+		assert.Equal(t, "-01:30:00", s)
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		m := &typesModel{
+			Email: "bytes@example.com",
+			Data:  Bytes{0x00, 0x01, 0xfe, 0xff},
+		}
+		require.NoError(t, db.Insert(ctx, m))
+
+		var got typesModel
+		assert.NoError(t, db.Select(ctx, &got, m.GetID()))
+		assertEqualTypes(t, m, &got)
+
+		var n int
+		assert.NoError(t, db.Get(ctx, &n, "SELECT octet_length(data) FROM types_test WHERE id = $1", m.GetID()))
+		assert.Equal(t, 4, n)
+
+		var b LimitedBytes[testSizeLimit]
+		assert.NoError(t, db.Get(ctx, &b, "SELECT data FROM types_test WHERE id = $1", m.GetID()))
+		assert.Equal(t, LimitedBytes[testSizeLimit]{0x00, 0x01, 0xfe, 0xff}, b)
+
+		_, err := db.Exec(ctx, "UPDATE types_test SET data = $1 WHERE id = $2", LimitedBytes[testSizeLimit]("too large"), m.GetID())
+		assert.ErrorIs(t, err, ErrTooLarge)
+	})
+
+	t.Run("inet", func(t *testing.T) {
+		for i, tc := range []struct {
+			ip      string
+			network string
+		}{
+			{"192.168.0.1", "192.168.0.0/24"},
+			{"2001:db8::1", "2001:db8::/32"},
+		} {
+			m := &typesModel{
+				Email:   CIText(fmt.Sprintf("inet%d@example.com", i)),
+				IP:      Inet(netip.MustParseAddr(tc.ip)),
+				Network: CIDR(netip.MustParsePrefix(tc.network)),
+			}
+			require.NoError(t, db.Insert(ctx, m))
+
+			var got typesModel
+			assert.NoError(t, db.Select(ctx, &got, m.GetID()))
+			assertEqualTypes(t, m, &got)
+
+			var contained bool
+			assert.NoError(t, db.Get(ctx, &contained, "SELECT ip << network FROM types_test WHERE id = $1", m.GetID()))
+			assert.True(t, contained)
+		}
+
+		var c CIDR
+		assert.NoError(t, db.Get(ctx, &c, "SELECT '10.0.0.1/8'::inet"))
+		assert.Equal(t, CIDR(netip.MustParsePrefix("10.0.0.1/8")), c)
+		var ip Inet
+		assert.Error(t, db.Get(ctx, &ip, "SELECT '10.0.0.1/8'::inet"))
+		assert.NoError(t, db.Get(ctx, &ip, "SELECT NULL::inet"))
+		assert.False(t, ip.Addr().IsValid())
+	})
+
+	t.Run("money", func(t *testing.T) {
+		for i, p := range []Money{0, 1, 123456, -99, 100000000} {
+			m := &typesModel{
+				Email: CIText(fmt.Sprintf("money%d@example.com", i)),
+				Price: p,
+			}
+			require.NoError(t, db.Insert(ctx, m))
+
+			var got typesModel
+			assert.NoError(t, db.Select(ctx, &got, m.GetID()))
+			assertEqualTypes(t, m, &got)
+		}
+
+		var p Money
+		assert.NoError(t, db.Get(ctx, &p, "SELECT '1234.56'::money"))
+		assert.Equal(t, Money(123456), p)
+		assert.NoError(t, db.Get(ctx, &p, "SELECT NULL::money"))
+		assert.Equal(t, Money(0), p)
+
+		var n int
+		assert.NoError(t, db.Get(ctx, &n, "SELECT count(*) FROM types_test WHERE price > $1", Money(100)))
+		assert.Equal(t, 2, n)
+	})
+}
+
+func TestCIText(t *testing.T) {
+	var c CIText
+	assert.NoError(t, c.Scan("Foo"))
+	assert.Equal(t, CIText("Foo"), c)
+	assert.NoError(t, c.Scan([]byte("Bar")))
+	assert.Equal(t, CIText("Bar"), c)
+	assert.NoError(t, c.Scan(nil))
+	assert.Equal(t, CIText(""), c)
+	assert.Error(t, c.Scan(123))
+
+	v, err := CIText("Foo").Value()
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Value("Foo"), v)
+	assert.Equal(t, "Foo", CIText("Foo").String())
+	assert.True(t, CIText("Foo").Equal("fOO"))
+	assert.False(t, CIText("Foo").Equal("bar"))
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		name  string
+		d     Duration
+		value driver.Value
+	}{
+		{"zero", 0, "00:00:00"},
+		{"sub-second", Duration(1500 * time.Millisecond), "00:00:01.500000"},
+		{"microseconds", Duration(time.Microsecond), "00:00:00.000001"},
+		{"multi-day", Duration(50*time.Hour + 30*time.Minute), "50:30:00"},
+		{"negative", Duration(-90 * time.Minute), "-01:30:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.d.Value()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.value, v)
+
+			var got Duration
+			assert.NoError(t, got.Scan(v))
+			assert.Equal(t, tt.d, got)
+		})
+	}
+
+	t.Run("scan", func(t *testing.T) {
+		var d Duration
+		assert.NoError(t, d.Scan("2 days 03:00:00"))
+		assert.Equal(t, Duration(51*time.Hour), d)
+		assert.NoError(t, d.Scan([]byte("1 mon -1 days")))
+		assert.Equal(t, Duration(29*24*time.Hour), d)
+		assert.NoError(t, d.Scan(nil))
+		assert.Equal(t, Duration(0), d)
+		assert.Error(t, d.Scan(123))
+		assert.Error(t, d.Scan("foo"))
+		assert.Equal(t, "1h30m0s", Duration(90*time.Minute).String())
+	})
+}
+
+func TestTimestamp(t *testing.T) {
+	now := time.Date(2024, 5, 17, 10, 30, 15, 123456789, time.FixedZone("CEST", 2*60*60))
+	ts := NewTimestamp(now)
+	assert.Equal(t, time.Date(2024, 5, 17, 8, 30, 15, 123456000, time.UTC), ts.Time)
+	assert.True(t, ts.Equal(now.Truncate(time.Microsecond)))
+	assert.Equal(t, Timestamp{}, NewTimestamp(time.Time{}))
+
+	v, err := Timestamp{Time: now}.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Value(ts.Time), v)
+	v, err = Timestamp{}.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	t.Run("scan", func(t *testing.T) {
+		var got Timestamp
+		assert.NoError(t, got.Scan(now))
+		assert.Equal(t, ts, got)
+		assert.NoError(t, got.Scan("2024-05-17 10:30:15.123456+02"))
+		assert.Equal(t, ts, got)
+		assert.NoError(t, got.Scan([]byte("2024-05-17 08:30:15.123456")))
+		assert.Equal(t, ts, got)
+		assert.NoError(t, got.Scan(nil))
+		assert.Equal(t, Timestamp{}, got)
+		assert.Error(t, got.Scan("infinity"))
+		assert.Error(t, got.Scan("foo"))
+		assert.Error(t, got.Scan(123))
+	})
+}
+
+func TestDB_Timestamp(t *testing.T) {
+	db, err := New(postgresDataSource)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	ts := NewTimestamp(time.Now())
+	for _, typ := range []string{"timestamptz", "timestamp"} {
+		var got Timestamp
+		require.NoError(t, db.Get(ctx, &got, "SELECT $1::"+typ, ts))
+		assert.Equal(t, ts, got, typ)
+	}
+
+	var got Timestamp
+	require.NoError(t, db.Get(ctx, &got, "SELECT NULL::timestamptz"))
+	assert.True(t, got.IsZero())
+}
+
+type testSizeLimit struct{}
+
+func (testSizeLimit) MaxSize() int { return 4 }
+
+func TestBytes(t *testing.T) {
+	src := []byte("foo")
+	var b Bytes
+	assert.NoError(t, b.Scan(src))
+	assert.Equal(t, Bytes("foo"), b)
+	src[0] = 'b'
+	assert.Equal(t, Bytes("foo"), b)
+	assert.NoError(t, b.Scan("bar"))
+	assert.Equal(t, Bytes("bar"), b)
+	assert.NoError(t, b.Scan(nil))
+	assert.Nil(t, b)
+	assert.Error(t, b.Scan(123))
+
+	v, err := Bytes("foo").Value()
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Value([]byte("foo")), v)
+	v, err = Bytes(nil).Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	t.Run("limited", func(t *testing.T) {
+		var b LimitedBytes[testSizeLimit]
+		assert.NoError(t, b.Scan([]byte("larger than the limit")))
+		assert.Equal(t, LimitedBytes[testSizeLimit]("larger than the limit"), b)
+		assert.NoError(t, b.Scan(nil))
+		assert.Nil(t, b)
+		assert.Error(t, b.Scan(123))
+
+		v, err := LimitedBytes[testSizeLimit]("four").Value()
+		assert.NoError(t, err)
+		assert.Equal(t, driver.Value([]byte("four")), v)
+		v, err = LimitedBytes[testSizeLimit](nil).Value()
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+
+		v, err = LimitedBytes[testSizeLimit]("fives").Value()
+		assert.ErrorIs(t, err, ErrTooLarge)
+		assert.EqualError(t, err, "value exceeds the maximum size: 5 bytes, the maximum is 4")
+		assert.Nil(t, v)
+	})
+}
+
+func TestInet(t *testing.T) {
+	tests := []struct {
+		name  string
+		ip    Inet
+		value driver.Value
+	}{
+		{"ipv4", Inet(netip.MustParseAddr("192.168.0.1")), "192.168.0.1"},
+		{"ipv6", Inet(netip.MustParseAddr("2001:db8::1")), "2001:db8::1"},
+		{"zero", Inet{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.ip.Value()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.value, v)
+
+			var got Inet
+			assert.NoError(t, got.Scan(v))
+			assert.Equal(t, tt.ip, got)
+		})
+	}
+
+	var ip Inet
+	assert.NoError(t, ip.Scan([]byte("10.0.0.1/32")))
+	assert.Equal(t, "10.0.0.1", ip.String())
+	assert.Equal(t, netip.MustParseAddr("10.0.0.1"), ip.Addr())
+	assert.Error(t, ip.Scan("10.0.0.1/8"))
+	assert.Error(t, ip.Scan("foo"))
+	assert.Error(t, ip.Scan(123))
+}
+
+func TestCIDR(t *testing.T) {
+	tests := []struct {
+		name  string
+		c     CIDR
+		value driver.Value
+	}{
+		{"ipv4", CIDR(netip.MustParsePrefix("192.168.0.0/24")), "192.168.0.0/24"},
+		{"ipv6", CIDR(netip.MustParsePrefix("2001:db8::/32")), "2001:db8::/32"},
+		{"zero", CIDR{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.c.Value()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.value, v)
+
+			var got CIDR
+			assert.NoError(t, got.Scan(v))
+			assert.Equal(t, tt.c, got)
+		})
+	}
+
+	var c CIDR
+	assert.NoError(t, c.Scan([]byte("10.0.0.1")))
+	assert.Equal(t, "10.0.0.1/32", c.String())
+	assert.Equal(t, netip.MustParsePrefix("10.0.0.1/32"), c.Prefix())
+	assert.Error(t, c.Scan("foo"))
+	assert.Error(t, c.Scan(123))
+}
+
+func TestMoney(t *testing.T) {
+	tests := []struct {
+		name  string
+		m     Money
+		value driver.Value
+	}{
+		{"zero", 0, "0.00"},
+		{"cents", 5, "0.05"},
+		{"dollars", 123456, "1234.56"},
+		{"negative", -123456, "-1234.56"},
+		{"negative cents", -5, "-0.05"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.m.Value()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.value, v)
+			assert.Equal(t, tt.value, tt.m.String())
+
+			var got Money
+			assert.NoError(t, got.Scan(v))
+			assert.Equal(t, tt.m, got)
+		})
+	}
+
+	t.Run("scan", func(t *testing.T) {
+		tests := []struct {
+			src       any
+			want      Money
+			assertion assert.ErrorAssertionFunc
+		}{
+			{"$1,234.56", 123456, assert.NoError},
+			{[]byte("-$1,234.56"), -123456, assert.NoError},
+			{"($0.99)", -99, assert.NoError},
+			{"1.234,56 €", 123456, assert.NoError},
+			{"1234", 123400, assert.NoError},
+			{nil, 0, assert.NoError},
+			{"$1.5", 0, assert.Error},
+			{"$", 0, assert.Error},
+			{"99999999999999999999.00", 0, assert.Error},
+			{123, 0, assert.Error},
+		}
+		for _, tt := range tests {
+			var got Money
+			tt.assertion(t, got.Scan(tt.src), tt.src)
+			assert.Equal(t, tt.want, got, tt.src)
+		}
+	})
+}
@@ -0,0 +1,289 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-sqlx/sqlx/reflectx"
+	"go.step.sm/qb"
+)
+
+// upsertQuery returns the named insert query with the given conflict action
+// that returns all the columns of the model. On update, the columns in exprs
+// are set to the given expressions instead of their excluded values.
+func upsertQuery(b *qb.QueryBuilder, arg Model, conflictColumns []string, doUpdate bool, exprs map[string]string) (string, error) {
+	if len(conflictColumns) == 0 {
+		return "", errors.New("upsert requires at least one conflict column")
+	}
+	for _, c := range conflictColumns {
+		if !hasColumn(b, c) {
+			return "", fmt.Errorf("column %q not found in %s", c, b.Table)
+		}
+	}
+
+	_, withID := arg.(ModelWithExecInsert)
+	generated := generatedColumns(arg)
+	var columns, values, updatable, updates []string
+	for _, c := range b.Columns {
+		if (c == b.PrimaryKey && !withID) || slices.Contains(generated, c) {
+			continue
+		}
+		columns = append(columns, c)
+		values = append(values, ":"+c)
+		if c != b.PrimaryKey && c != "created_at" && !slices.Contains(conflictColumns, c) {
+			updatable = append(updatable, c)
+			if expr, ok := exprs[c]; ok {
+				// Colons are escaped, so they are not taken as named parameters.
+				updates = append(updates, c+" = "+strings.ReplaceAll(expr, ":", "::"))
+			} else {
+				updates = append(updates, c+" = EXCLUDED."+c)
+			}
+		}
+	}
+	for c, expr := range exprs {
+		switch {
+		case !hasColumn(b, c):
+			return "", fmt.Errorf("column %q not found in %s", c, b.Table)
+		case !slices.Contains(updatable, c):
+			return "", fmt.Errorf("column %q cannot be updated", c)
+		case strings.TrimSpace(expr) == "":
+			return "", fmt.Errorf("empty expression for column %q", c)
+		}
+	}
+
+	action := "DO NOTHING"
+	if doUpdate {
+		action = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s RETURNING %s",
+		b.Table, strings.Join(columns, ", "), strings.Join(values, ", "),
+		strings.Join(conflictColumns, ", "), action, strings.Join(b.Columns, ", "),
+	), nil
+}
+
+// Upsert inserts the given model in the database or, if a row with the same
+// values in the conflict columns already exists, updates all the columns of
+// that row but the id and created_at. The model is populated with the row
+// returned by the database, so after an update it contains the id and
+// created_at of the existing row.
+//
+// The conflict columns must match a unique index or constraint in the table.
+func (d *DB) Upsert(ctx context.Context, arg Model, conflictColumns ...string) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "upsert", arg, "", "")
+	return d.upsert(ctx, arg, conflictColumns, true, upsertOptions{})
+}
+
+type upsertOptions struct {
+	exprs    map[string]string
+	inserted *bool
+}
+
+// UpsertOption is the type of options that can be used to modify an upsert.
+type UpsertOption func(*upsertOptions)
+
+// ConflictUpdate sets the column to the given SQL expression, instead of to
+// the value in the model, when the upsert updates an existing row. The
+// expression can reference the existing row with the table name and the
+// proposed one with EXCLUDED, for example, to accumulate a counter:
+//
+//	ConflictUpdate("count", "counters.count + EXCLUDED.count")
+//
+// The expression is added verbatim to the query, so it must never include
+// user input.
+func ConflictUpdate(column, expr string) UpsertOption {
+	return func(o *upsertOptions) {
+		if o.exprs == nil {
+			o.exprs = make(map[string]string)
+		}
+		o.exprs[column] = expr
+	}
+}
+
+// ReportInserted sets inserted to true if the upsert inserted a new row, or to
+// false if it updated an existing one. It uses the xmax system column of the
+// returned row, that is 0 only for inserted rows, so it is accurate even if the
+// update does not modify any value.
+func ReportInserted(inserted *bool) UpsertOption {
+	return func(o *upsertOptions) {
+		o.inserted = inserted
+	}
+}
+
+// UpsertWith is like Upsert, but the upsert can be customized with the given
+// options, see ConflictUpdate and ReportInserted. The columns without an
+// update expression are set to the values in the model.
+func (d *DB) UpsertWith(ctx context.Context, arg Model, conflictColumns []string, opts ...UpsertOption) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "upsert", arg, "", "")
+	o := new(upsertOptions)
+	for _, fn := range opts {
+		fn(o)
+	}
+	return d.upsert(ctx, arg, conflictColumns, true, *o)
+}
+
+// InsertOrGet inserts the given model in the database or, if a row with the
+// same values in the conflict columns already exists, leaves that row
+// untouched. In both cases the model is populated with the authoritative row in
+// the database, the new one or the existing one.
+//
+// Unlike Upsert, that uses `ON CONFLICT DO UPDATE`, InsertOrGet uses `ON
+// CONFLICT DO NOTHING`, and as the database does not return a row on conflict,
+// the existing row is read with a select by the conflict columns. This select
+// includes soft-deleted rows, as they also cause a conflict.
+//
+// The conflict columns must match a unique index or constraint in the table.
+func (d *DB) InsertOrGet(ctx context.Context, arg Model, conflictColumns ...string) (err error) {
+	defer wrapQueryError(d.queryErrors, &err, "insert or get", arg, "", "")
+	return d.upsert(ctx, arg, conflictColumns, false, upsertOptions{})
+}
+
+func (d *DB) upsert(ctx context.Context, arg Model, conflictColumns []string, doUpdate bool, o upsertOptions) error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	if err := validate(arg); err != nil {
+		return err
+	}
+	b, err := queryBuilder(arg)
+	if err != nil {
+		return err
+	}
+	insertQuery, err := upsertQuery(b, arg, conflictColumns, doUpdate, o.exprs)
+	if err != nil {
+		return err
+	}
+	if o.inserted != nil {
+		// The returned columns are always the last part of the query.
+		insertQuery += ", (xmax = 0) AS inserted"
+	}
+
+	t0 := d.clock.Now()
+	arg.SetCreatedAt(t0)
+	arg.SetUpdatedAt(t0)
+
+	query, qargs, err := d.db.BindNamed(insertQuery, arg)
+	if err != nil {
+		return err
+	}
+
+	ctx, ex, release, err := d.acquire(ctx, d.writeTimeout)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if o.inserted != nil {
+		return d.getInserted(ctx, ex, arg, o.inserted, query, qargs...)
+	}
+	err = ex.GetContext(ctx, arg, query, qargs...)
+	if doUpdate || !IsErrNotFound(err) {
+		return err
+	}
+
+	// On conflict DO NOTHING does not return the existing row.
+	var where []string
+	for _, c := range conflictColumns {
+		where = append(where, c+" = :"+c)
+	}
+	selectQuery := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(b.Columns, ", "), b.Table, strings.Join(where, " AND "))
+	query, qargs, err = d.db.BindNamed(selectQuery, arg)
+	if err != nil {
+		return err
+	}
+	return ex.GetContext(ctx, arg, query, qargs...)
+}
+
+// getInserted runs the given upsert query, that returns the columns of the
+// model followed by the inserted flag, and scans the row into arg and inserted.
+func (d *DB) getInserted(ctx context.Context, ex executor, arg Model, inserted *bool, query string, args ...any) error {
+	rows, err := ex.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(arg)
+	last := len(columns) - 1
+	traversals := d.db.Mapper.TraversalsByName(v.Type(), columns[:last])
+	dest := make([]any, len(columns))
+	for i, t := range traversals {
+		if len(t) == 0 {
+			return fmt.Errorf("missing destination name %s in %T", columns[i], arg)
+		}
+		dest[i] = reflectx.FieldByIndexes(v, t).Addr().Interface()
+	}
+	dest[last] = inserted
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// UpsertBatch upserts the given models like Upsert, one statement per model, in
+// a single transaction, see RunInTx. Each model is populated with the row
+// returned by the database, including the id. If any of the upserts fails, or
+// the context is canceled, the transaction is rolled back and none of the rows
+// is modified.
+//
+// The conflict columns must match a unique index or constraint in the table of
+// every model.
+func (d *DB) UpsertBatch(ctx context.Context, args []Model, conflictColumns ...string) (err error) {
+	// current is the model being upserted, if any
+	var current Model
+	defer func() {
+		wrapQueryError(d.queryErrors, &err, "upsert batch", current, "", "")
+	}()
+
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	queries := make([]string, len(args))
+	for i, a := range args {
+		current = a
+		if err := validate(a); err != nil {
+			return err
+		}
+		b, err := queryBuilder(a)
+		if err != nil {
+			return err
+		}
+		if queries[i], err = upsertQuery(b, a, conflictColumns, true, nil); err != nil {
+			return err
+		}
+	}
+	current = nil
+	t0 := d.clock.Now()
+
+	return d.RunInTx(ctx, func(ctx context.Context, tx *Tx) error {
+		for i, a := range args {
+			current = a
+			a.SetCreatedAt(t0)
+			a.SetUpdatedAt(t0)
+			query, qargs, err := tx.tx.BindNamed(queries[i], a)
+			if err != nil {
+				return err
+			}
+			if err := tx.tx.GetContext(ctx, a, tx.track(tagQuery(ctx, query)), qargs...); err != nil {
+				return err
+			}
+		}
+		current = nil
+		return nil
+	})
+}
@@ -0,0 +1,221 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/sequel/clock"
+)
+
+func Test_upsertQuery(t *testing.T) {
+	b, err := queryBuilder(&personModel{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		arg             Model
+		conflictColumns []string
+		doUpdate        bool
+		exprs           map[string]string
+		want            string
+		assertion       assert.ErrorAssertionFunc
+	}{
+		{"ok update", &personModel{}, []string{"email"}, true, nil, "INSERT INTO person_test (created_at, updated_at, deleted_at, name, email) VALUES (:created_at, :updated_at, :deleted_at, :name, :email) ON CONFLICT (email) DO UPDATE SET updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at, name = EXCLUDED.name RETURNING id, created_at, updated_at, deleted_at, name, email", assert.NoError},
+		{"ok nothing", &personModel{}, []string{"email"}, false, nil, "INSERT INTO person_test (created_at, updated_at, deleted_at, name, email) VALUES (:created_at, :updated_at, :deleted_at, :name, :email) ON CONFLICT (email) DO NOTHING RETURNING id, created_at, updated_at, deleted_at, name, email", assert.NoError},
+		{"ok with id", &personModelExtra{}, []string{"id"}, true, nil, "INSERT INTO person_test (id, created_at, updated_at, deleted_at, name, email) VALUES (:id, :created_at, :updated_at, :deleted_at, :name, :email) ON CONFLICT (id) DO UPDATE SET updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at, name = EXCLUDED.name, email = EXCLUDED.email RETURNING id, created_at, updated_at, deleted_at, name, email", assert.NoError},
+		{"ok exprs", &personModel{}, []string{"email"}, true, map[string]string{"name": "person_test.name || ', ' || EXCLUDED.name", "deleted_at": "NULL::timestamptz"}, "INSERT INTO person_test (created_at, updated_at, deleted_at, name, email) VALUES (:created_at, :updated_at, :deleted_at, :name, :email) ON CONFLICT (email) DO UPDATE SET updated_at = EXCLUDED.updated_at, deleted_at = NULL::::timestamptz, name = person_test.name || ', ' || EXCLUDED.name RETURNING id, created_at, updated_at, deleted_at, name, email", assert.NoError},
+		{"fail no columns", &personModel{}, nil, true, nil, "", assert.Error},
+		{"fail missing column", &personModel{}, []string{"email; DROP TABLE person_test"}, true, nil, "", assert.Error},
+		{"fail expr missing column", &personModel{}, []string{"email"}, true, map[string]string{"count": "1"}, "", assert.Error},
+		{"fail expr conflict column", &personModel{}, []string{"email"}, true, map[string]string{"email": "EXCLUDED.email"}, "", assert.Error},
+		{"fail expr primary key", &personModel{}, []string{"email"}, true, map[string]string{"id": "EXCLUDED.id"}, "", assert.Error},
+		{"fail expr empty", &personModel{}, []string{"email"}, true, map[string]string{"name": " "}, "", assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := upsertQuery(b, tt.arg, tt.conflictColumns, tt.doUpdate, tt.exprs)
+			tt.assertion(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDB_Upsert(t *testing.T) {
+	t0 := time.Now().UTC().Truncate(time.Second)
+	db, err := New(postgresDataSource, WithClock(clock.NewMock(t0)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t1 := t0.Add(time.Hour)
+	db1, err := New(postgresDataSource, WithClock(clock.NewMock(t1)))
+	require.NoError(t, err)
+	defer db1.Close()
+
+	p1 := &personModel{Name: "Lucky Luke", Email: NullString("lucky@example.com")}
+
+	t.Run("insert", func(t *testing.T) {
+		assert.NoError(t, db.Upsert(ctx, p1, "email"))
+		assert.NotEmpty(t, p1.GetID())
+
+		var got personModel
+		assert.NoError(t, db.Select(ctx, &got, p1.GetID()))
+		assertEqualPerson(t, p1, &got)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		p := &personModel{Name: "Lucky Luke Jr.", Email: NullString("lucky@example.com")}
+		assert.NoError(t, db1.Upsert(ctx, p, "email"))
+		assert.Equal(t, p1.GetID(), p.GetID())
+		assert.Equal(t, t0, p.CreatedAt.UTC())
+		assert.Equal(t, t1, p.UpdatedAt.UTC())
+
+		var got personModel
+		assert.NoError(t, db.Select(ctx, &got, p1.GetID()))
+		assertEqualPerson(t, p, &got)
+		p1 = &got
+	})
+
+	t.Run("upsertWith", func(t *testing.T) {
+		p := &personModel{Name: "Rantanplan", Email: NullString("lucky@example.com")}
+		assert.NoError(t, db1.UpsertWith(ctx, p, []string{"email"},
+			ConflictUpdate("name", "person_test.name || ' & ' || EXCLUDED.name::text")))
+		assert.Equal(t, p1.GetID(), p.GetID())
+		assert.Equal(t, "Lucky Luke Jr. & Rantanplan", p.Name)
+
+		p2 := &personModel{Name: "Jolly Jumper", Email: NullString("jolly@example.com")}
+		assert.NoError(t, db1.UpsertWith(ctx, p2, []string{"email"},
+			ConflictUpdate("name", "person_test.name || ' & ' || EXCLUDED.name")))
+		assert.NotEqual(t, p1.GetID(), p2.GetID())
+		assert.Equal(t, "Jolly Jumper", p2.Name)
+
+		assert.Error(t, db1.UpsertWith(ctx, p2, []string{"email"}, ConflictUpdate("email", "EXCLUDED.email")))
+
+		var got personModel
+		assert.NoError(t, db.Select(ctx, &got, p1.GetID()))
+		assertEqualPerson(t, p, &got)
+		p1 = &got
+	})
+
+	t.Run("reportInserted", func(t *testing.T) {
+		var inserted bool
+		p := &personModel{Name: "Averell Dalton", Email: NullString("averell@example.com")}
+		assert.NoError(t, db.UpsertWith(ctx, p, []string{"email"}, ReportInserted(&inserted)))
+		assert.True(t, inserted)
+		assert.NotEmpty(t, p.GetID())
+		assert.Equal(t, t0, p.CreatedAt.UTC())
+
+		// The flag is also false if the update does not change any value.
+		for _, name := range []string{"Averell Dalton", "Averell"} {
+			pp := &personModel{Name: name, Email: NullString("averell@example.com")}
+			assert.NoError(t, db1.UpsertWith(ctx, pp, []string{"email"}, ReportInserted(&inserted)))
+			assert.False(t, inserted)
+			assert.Equal(t, p.GetID(), pp.GetID())
+			assert.Equal(t, name, pp.Name)
+			assert.Equal(t, t0, pp.CreatedAt.UTC())
+			assert.Equal(t, t1, pp.UpdatedAt.UTC())
+		}
+
+		assert.Error(t, db.UpsertWith(ctx, p, []string{"phone"}, ReportInserted(&inserted)))
+	})
+
+	t.Run("insertOrGet insert", func(t *testing.T) {
+		p := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+		assert.NoError(t, db.InsertOrGet(ctx, p, "email"))
+		assert.NotEmpty(t, p.GetID())
+		assert.NotEqual(t, p1.GetID(), p.GetID())
+	})
+
+	t.Run("insertOrGet get", func(t *testing.T) {
+		p := &personModel{Name: "Other Name", Email: NullString("lucky@example.com")}
+		assert.NoError(t, db1.InsertOrGet(ctx, p, "email"))
+		assertEqualPerson(t, p1, p)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		assert.Error(t, db.Upsert(ctx, &personModel{Name: "Lucky Luke"}, "name"))
+		assert.Error(t, db.Upsert(ctx, &personModel{Name: "Lucky Luke"}))
+
+		rdb, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer rdb.Close()
+		assert.ErrorIs(t, rdb.Upsert(ctx, &personModel{Name: "Lucky Luke"}, "email"), ErrReadOnly)
+		assert.ErrorIs(t, rdb.InsertOrGet(ctx, &personModel{Name: "Lucky Luke"}, "email"), ErrReadOnly)
+	})
+}
+
+func TestDB_UpsertBatch(t *testing.T) {
+	t0 := time.Now().UTC().Truncate(time.Second)
+	db, err := New(postgresDataSource, WithClock(clock.NewMock(t0)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, "DELETE FROM person_test")
+		assert.NoError(t, err)
+	})
+
+	t1 := t0.Add(time.Hour)
+	db1, err := New(postgresDataSource, WithClock(clock.NewMock(t1)))
+	require.NoError(t, err)
+	defer db1.Close()
+
+	p1 := &personModel{Name: "Joe Dalton", Email: NullString("joe@example.com")}
+	require.NoError(t, db.Insert(ctx, p1))
+
+	t.Run("ok", func(t *testing.T) {
+		p2 := &personModel{Name: "Joe Dalton Jr.", Email: NullString("joe@example.com")}
+		p3 := &personModel{Name: "Jack Dalton", Email: NullString("jack@example.com")}
+		assert.NoError(t, db1.UpsertBatch(ctx, []Model{p2, p3}, "email"))
+
+		// p2 updates p1
+		assert.Equal(t, p1.GetID(), p2.GetID())
+		assert.Equal(t, t0, p2.CreatedAt.UTC())
+		assert.Equal(t, t1, p2.UpdatedAt.UTC())
+		assert.NotEmpty(t, p3.GetID())
+		assert.Equal(t, t1, p3.CreatedAt.UTC())
+
+		for _, p := range []*personModel{p2, p3} {
+			var got personModel
+			assert.NoError(t, db.Select(ctx, &got, p.GetID()))
+			assertEqualPerson(t, p, &got)
+		}
+	})
+
+	t.Run("ok empty", func(t *testing.T) {
+		assert.NoError(t, db.UpsertBatch(ctx, nil, "email"))
+	})
+
+	t.Run("fail rollback", func(t *testing.T) {
+		p4 := &personModel{Name: "William Dalton", Email: NullString("william@example.com")}
+		p5 := &personModel{Name: "Averell Dalton\x00", Email: NullString("averell@example.com")}
+		assert.Error(t, db.UpsertBatch(ctx, []Model{p4, p5}, "email"))
+		assert.NotEmpty(t, p4.GetID())
+		assert.ErrorIs(t, db.Select(ctx, &personModel{}, p4.GetID()), sql.ErrNoRows)
+	})
+
+	t.Run("fail validation", func(t *testing.T) {
+		assert.Error(t, db.UpsertBatch(ctx, []Model{&personModel{Name: "Lucky Luke"}}, "email; DROP TABLE person_test"))
+	})
+
+	t.Run("fail read only", func(t *testing.T) {
+		db, err := New(postgresDataSource, WithReadOnly())
+		require.NoError(t, err)
+		defer db.Close()
+		assert.ErrorIs(t, db.UpsertBatch(ctx, []Model{&personModel{}}, "email"), ErrReadOnly)
+	})
+}